@@ -0,0 +1,364 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/luno/gobridge/generator"
+	"github.com/luno/gobridge/plugin"
+	"github.com/luno/gobridge/reader"
+)
+
+// FileNames are the names of the config file looked for in the working directory.
+var FileNames = []string{"gobridge.yaml", "gobridge.yml", "gobridge.json"}
+
+// Config is the gobridge project configuration, read from a YAML or JSON file.
+type Config struct {
+	// Path is the absolute path of the config file, empty when not read from a file.
+	Path string `yaml:"-" json:"-"`
+	// Dir is the directory of the config file, which paths are relative to.
+	Dir string `yaml:"-" json:"-"`
+
+	// Module is the name of the Go module, read from the nearest go.mod file in Dir or its parents when not set.
+	Module string        `yaml:"module" json:"module"`
+	APIs   []API         `yaml:"apis" json:"apis"`
+	Types  []TypeMapping `yaml:"types" json:"types"`
+
+	// Templates is a directory of templates overriding the built in ones.
+	Templates string `yaml:"templates" json:"templates"`
+}
+
+// API describes an API package and the outputs generated from it. Outputs
+// which aren't set aren't generated.
+type API struct {
+	Package   string   `yaml:"package" json:"package"`             // Directory of the package declaring the API interface
+	Server    string   `yaml:"server" json:"server"`               // Go server file
+	Client    string   `yaml:"client" json:"client"`               // Go client file, requires server
+	TS        string   `yaml:"ts" json:"ts"`                       // Angular TypeScript service file
+	TSService string   `yaml:"ts_service" json:"ts_service"`       // Name of the TypeScript service, requires ts
+	OpenAPI   string   `yaml:"openapi" json:"openapi"`             // OpenAPI document file
+	Contract  string   `yaml:"contract_test" json:"contract_test"` // Go test of the server and client, requires both
+	Fake      string   `yaml:"fake" json:"fake"`                   // Go fakes of the API interfaces
+	TSMock    string   `yaml:"ts_mock" json:"ts_mock"`             // TypeScript mock of the service, requires ts
+	Plugins   []Plugin `yaml:"plugins" json:"plugins"`
+	Options   Options  `yaml:"options" json:"options"`
+}
+
+// Plugin is a generator run as a separate executable, see package plugin.
+type Plugin struct {
+	Name    string            `yaml:"name" json:"name"`       // Runs gobridge-gen-<name> from PATH
+	Path    string            `yaml:"path" json:"path"`       // Executable to run instead, relative to the config file
+	Out     string            `yaml:"out" json:"out"`         // Directory the generated files are written to
+	Options map[string]string `yaml:"options" json:"options"` // Passed to the plugin
+}
+
+// Options configure the wire format of an API. They apply to all of its outputs.
+type Options struct {
+	Int64AsString bool `yaml:"int64_string" json:"int64_string"` // Encode int64 and uint64 as JSON strings
+	Passthrough   bool `yaml:"passthrough" json:"passthrough"`   // Send single struct parameters and results as the bodies
+}
+
+// TypeMapping tells gobridge how a Go type declared outside of the API is
+// encoded, for types like money.Amount that marshal to a string.
+type TypeMapping struct {
+	Go       string                 `yaml:"go" json:"go"`               // Import path and type name, e.g. "example.com/money.Amount"
+	TS       string                 `yaml:"ts" json:"ts"`               // TypeScript type, e.g. "string" or "Amount"
+	TSImport string                 `yaml:"ts_import" json:"ts_import"` // Optional statement importing the TypeScript type
+	OpenAPI  map[string]interface{} `yaml:"openapi" json:"openapi"`     // OpenAPI schema fragment, defaults to {}
+}
+
+// Find returns the path of the config file in dir.
+func Find(dir string) (string, error) {
+	for _, name := range FileNames {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+
+	return "", fmt.Errorf("no %s found in %s", strings.Join(FileNames, ", "), dir)
+}
+
+// Load reads the config file, parsing it as JSON when it has a .json
+// extension and as YAML otherwise.
+func Load(path string) (*Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var c Config
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(b, &c)
+	} else {
+		err = yaml.Unmarshal(b, &c)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	c.Dir = filepath.Dir(path)
+	c.Path, err = filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Module == "" && len(c.APIs) > 0 {
+		c.Module, err = FindModule(c.Dir)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	err = c.validate()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return &c, nil
+}
+
+// ModulePath returns the module name declared by the go.mod file in dir.
+func ModulePath(dir string) (string, error) {
+	b, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return "", err
+	}
+
+	for _, line := range strings.Split(string(b), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == "module" {
+			return strings.Trim(fields[1], `"`), nil
+		}
+	}
+
+	return "", fmt.Errorf("no module directive in %s", filepath.Join(dir, "go.mod"))
+}
+
+// FindModule returns the name of the module which dir is in, declared by the
+// nearest go.mod file in dir or its parents.
+func FindModule(dir string) (string, error) {
+	root, err := reader.ModuleRoot(dir)
+	if err != nil {
+		return "", err
+	}
+
+	return ModulePath(root)
+}
+
+func (c *Config) validate() error {
+	for i, a := range c.APIs {
+		if a.Package == "" {
+			return fmt.Errorf("apis[%d]: package is required", i)
+		}
+
+		if a.Client != "" && a.Server == "" {
+			return fmt.Errorf("apis[%d]: client requires server to be set", i)
+		}
+
+		if a.Contract != "" && (a.Server == "" || a.Client == "") {
+			return fmt.Errorf("apis[%d]: contract_test requires server and client to be set", i)
+		}
+
+		if a.TSMock != "" && a.TS == "" {
+			return fmt.Errorf("apis[%d]: ts_mock requires ts to be set", i)
+		}
+
+		if a.TS != "" && a.TSService == "" {
+			return fmt.Errorf("apis[%d]: ts requires ts_service to be set", i)
+		}
+
+		for j, p := range a.Plugins {
+			err := p.Validate()
+			if err != nil {
+				return fmt.Errorf("apis[%d].plugins[%d]: %w", i, j, err)
+			}
+		}
+	}
+
+	for i, t := range c.Types {
+		if _, _, ok := splitGoType(t.Go); !ok {
+			return fmt.Errorf("types[%d]: go must be an import path and type name like example.com/money.Amount, got %q", i, t.Go)
+		}
+
+		if t.TS == "" {
+			return fmt.Errorf("types[%d]: ts is required for %s", i, t.Go)
+		}
+	}
+
+	return nil
+}
+
+// Validate checks the plugin has a name or path and an output directory.
+func (p Plugin) Validate() error {
+	if p.Name == "" && p.Path == "" {
+		return errors.New("name or path is required")
+	}
+
+	if p.Out == "" {
+		return fmt.Errorf("out is required for plugin %s", p.Label())
+	}
+
+	return nil
+}
+
+// Label returns the name the plugin is reported by.
+func (p Plugin) Label() string {
+	if p.Name != "" {
+		return p.Name
+	}
+
+	return filepath.Base(p.Path)
+}
+
+// Executable returns the executable of the plugin.
+func (p Plugin) Executable() string {
+	if p.Path == "" {
+		return plugin.Executable(p.Name)
+	}
+
+	// Make sure relative paths aren't looked up in PATH
+	if !filepath.IsAbs(p.Path) && !strings.ContainsRune(p.Path, filepath.Separator) {
+		return "." + string(filepath.Separator) + p.Path
+	}
+
+	return p.Path
+}
+
+// TypeMappings returns the type mappings in the form used by the generators.
+func (c *Config) TypeMappings() []generator.TypeMapping {
+	var res []generator.TypeMapping
+	for _, t := range c.Types {
+		imp, name, _ := splitGoType(t.Go)
+
+		openAPI := t.OpenAPI
+		if openAPI == nil {
+			openAPI = make(map[string]interface{})
+		}
+
+		res = append(res, generator.TypeMapping{
+			GoImport: imp,
+			GoType:   name,
+			TS:       t.TS,
+			TSImport: t.TSImport,
+			OpenAPI:  openAPI,
+		})
+	}
+
+	return res
+}
+
+// GeneratorOptions returns the generator options for the API.
+func (c *Config) GeneratorOptions(a API) []generator.Option {
+	opts := []generator.Option{generator.WithTypeMappings(c.TypeMappings()...)}
+	if a.Options.Int64AsString {
+		opts = append(opts, generator.WithInt64AsString())
+	}
+
+	if a.Options.Passthrough {
+		opts = append(opts, generator.WithPassthrough())
+	}
+
+	if c.Templates != "" {
+		opts = append(opts, generator.WithTemplates(c.Templates))
+	}
+
+	return opts
+}
+
+// splitGoType splits "example.com/money.Amount" into its import path and
+// type name.
+func splitGoType(s string) (importPath string, name string, ok bool) {
+	i := strings.LastIndex(s, ".")
+	if i <= strings.LastIndex(s, "/") || i == len(s)-1 {
+		return "", "", false
+	}
+
+	return s[:i], s[i+1:], true
+}
+
+// AddAPI adds the API to the YAML config file, creating it if it doesn't
+// exist. Comments in an existing file are kept.
+func AddAPI(path string, a API) error {
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return fmt.Errorf("%s: adding APIs to JSON config files isn't supported, add it by hand", path)
+	}
+
+	var doc yaml.Node
+	b, err := os.ReadFile(path)
+	if err == nil {
+		err = yaml.Unmarshal(b, &doc)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("%s: expected a mapping at the top level", path)
+	}
+
+	var apis *yaml.Node
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == "apis" {
+			apis = root.Content[i+1]
+		}
+	}
+
+	if apis == nil {
+		apis = &yaml.Node{Kind: yaml.SequenceNode}
+		root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "apis"}, apis)
+	}
+
+	var n yaml.Node
+	err = n.Encode(a)
+	if err != nil {
+		return err
+	}
+
+	// Drop the zero values, leaving the outputs which are set
+	var content []*yaml.Node
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		v := n.Content[i+1]
+		if v.Value == "" || v.Value == "false" || (v.Kind == yaml.MappingNode && allZero(v)) {
+			continue
+		}
+		content = append(content, n.Content[i], v)
+	}
+	n.Content = content
+
+	apis.Content = append(apis.Content, &n)
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	err = enc.Encode(&doc)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
+
+func allZero(n *yaml.Node) bool {
+	for i := 1; i < len(n.Content); i += 2 {
+		if v := n.Content[i].Value; v != "" && v != "false" {
+			return false
+		}
+	}
+
+	return true
+}
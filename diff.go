@@ -0,0 +1,205 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/luno/gobridge/diff"
+	"github.com/luno/gobridge/reader"
+)
+
+// apiDiff is the JSON output of gobridge diff for an API.
+type apiDiff struct {
+	Package string        `json:"package"`
+	Changes []diff.Change `json:"changes"`
+}
+
+func runDiff(args []string) error {
+	fs := newFlagSet("diff", "", "report breaking changes to an API")
+	base := fs.String("base", "", "Git ref or directory of the module with the old version of the APIs")
+	asJSON := fs.Bool("json", false, "Print the changes as JSON")
+	err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if *base == "" {
+		fmt.Fprintln(os.Stderr, "-base is required")
+		fs.Usage()
+		return errUsage
+	}
+
+	// A directory is relative to the working directory, not the config file
+	baseDir := ""
+	if fi, err := os.Stat(*base); err == nil && fi.IsDir() {
+		baseDir, err = filepath.Abs(*base)
+		if err != nil {
+			return err
+		}
+	}
+
+	c, err := loadConfig(nil, "")
+	if err != nil {
+		return err
+	}
+
+	if baseDir == "" {
+		dir, err := extractRef(*base)
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+
+		prefix, err := git("rev-parse", "--show-prefix")
+		if err != nil {
+			return err
+		}
+		baseDir = filepath.Join(dir, strings.TrimSpace(string(prefix)))
+	}
+
+	var (
+		res         []apiDiff
+		anyBreaking bool
+	)
+	for _, a := range c.APIs {
+		old, err := parseIn(baseDir, a.Package, c.Module)
+		if errors.Is(err, os.ErrNotExist) {
+			logf("%s: new API, not in %s", a.Package, *base)
+			continue
+		} else if err != nil {
+			return fmt.Errorf("reading %s: %w", *base, err)
+		}
+
+		new, err := reader.ParseFile(a.Package, c.Module)
+		if err != nil {
+			return err
+		}
+
+		cs := diff.Compare(old.IR(), new.IR())
+		anyBreaking = anyBreaking || diff.Breaking(cs)
+		res = append(res, apiDiff{Package: a.Package, Changes: cs})
+	}
+
+	if *asJSON {
+		b, err := json.MarshalIndent(struct {
+			Breaking bool      `json:"breaking"`
+			APIs     []apiDiff `json:"apis"`
+		}{anyBreaking, res}, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+	} else {
+		for _, d := range res {
+			for _, c := range d.Changes {
+				fmt.Printf("%s: %s\n", d.Package, c)
+			}
+		}
+	}
+
+	if anyBreaking {
+		return errFailed
+	}
+
+	return nil
+}
+
+// parseIn reads the API package of the module in dir. The reader resolves
+// module imports relative to the working directory, so it's changed to dir
+// for the duration.
+func parseIn(dir, pkg, mod string) (*reader.Data, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.Chdir(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Chdir(wd)
+
+	if _, err := os.Stat(pkg); err != nil {
+		return nil, err
+	}
+
+	return reader.ParseFile(pkg, mod)
+}
+
+// extractRef writes the tree of the git ref to a temporary directory,
+// returning its path.
+func extractRef(ref string) (string, error) {
+	b, err := git("archive", "--format=tar", ref)
+	if err != nil {
+		return "", err
+	}
+
+	dir, err := os.MkdirTemp("", "gobridge")
+	if err != nil {
+		return "", err
+	}
+
+	tr := tar.NewReader(bytes.NewReader(b))
+	for {
+		h, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return dir, nil
+		} else if err != nil {
+			os.RemoveAll(dir)
+			return "", err
+		}
+
+		path := filepath.Join(dir, h.Name)
+		if !strings.HasPrefix(path, dir+string(filepath.Separator)) {
+			continue
+		}
+
+		switch h.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(path, os.ModePerm)
+		case tar.TypeReg:
+			err = writeFrom(path, tr)
+		}
+		if err != nil {
+			os.RemoveAll(dir)
+			return "", err
+		}
+	}
+}
+
+func writeFrom(path string, r io.Reader) error {
+	err := os.MkdirAll(filepath.Dir(path), os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(f, r)
+	return err
+}
+
+func git(args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+
+	return out, nil
+}
@@ -0,0 +1,283 @@
+// Package diff classifies the changes between two versions of an API as
+// breaking or compatible for clients already deployed against the old version.
+package diff
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/luno/gobridge/ir"
+)
+
+// Kind identifies the type of a change.
+type Kind string
+
+const (
+	MethodAdded       Kind = "method_added"
+	MethodRemoved     Kind = "method_removed"
+	ParamAdded        Kind = "param_added"
+	ParamRemoved      Kind = "param_removed"
+	ParamTypeChanged  Kind = "param_type_changed"
+	ResultAdded       Kind = "result_added"
+	ResultRemoved     Kind = "result_removed"
+	ResultTypeChanged Kind = "result_type_changed"
+	TypeAdded         Kind = "type_added"
+	TypeRemoved       Kind = "type_removed"
+	TypeKindChanged   Kind = "type_kind_changed"
+	FieldAdded        Kind = "field_added"
+	FieldRemoved      Kind = "field_removed"
+	FieldTypeChanged  Kind = "field_type_changed"
+	EnumValueAdded    Kind = "enum_value_added"
+	EnumValueRemoved  Kind = "enum_value_removed"
+	EnumValueChanged  Kind = "enum_value_changed"
+)
+
+// breaking are the kinds of change which break clients of the old version.
+// Additions are compatible as the JSON decoders ignore unknown fields and
+// default missing ones.
+var breaking = map[Kind]bool{
+	MethodRemoved:     true,
+	ParamRemoved:      true,
+	ParamTypeChanged:  true,
+	ResultRemoved:     true,
+	ResultTypeChanged: true,
+	TypeRemoved:       true,
+	TypeKindChanged:   true,
+	FieldRemoved:      true,
+	FieldTypeChanged:  true,
+	EnumValueRemoved:  true,
+	EnumValueChanged:  true,
+}
+
+// Change is a difference between the old and new versions of an API.
+type Change struct {
+	Kind     Kind   `json:"kind"`
+	Subject  string `json:"subject"` // What changed, e.g. "Example.HasPermission" or "backend.User.Name"
+	Breaking bool   `json:"breaking"`
+	Old      string `json:"old,omitempty"` // Type or value in the old version
+	New      string `json:"new,omitempty"` // Type or value in the new version
+}
+
+func (c Change) String() string {
+	level := "compatible"
+	if c.Breaking {
+		level = "breaking"
+	}
+
+	s := fmt.Sprintf("%s: %s: %s", level, c.Subject, c.Kind)
+	switch {
+	case c.Old != "" && c.New != "":
+		s += fmt.Sprintf(" from %s to %s", c.Old, c.New)
+	case c.Old != "":
+		s += fmt.Sprintf(" (was %s)", c.Old)
+	case c.New != "":
+		s += fmt.Sprintf(" (%s)", c.New)
+	}
+
+	return s
+}
+
+// Breaking reports whether any of the changes are breaking.
+func Breaking(cs []Change) bool {
+	for _, c := range cs {
+		if c.Breaking {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Compare returns the changes from the old to the new version of an API,
+// breaking changes first.
+func Compare(old, new *ir.API) []Change {
+	var cs changes
+	cs.methods(old.Interfaces, new.Interfaces)
+	cs.types(old.Types, new.Types)
+
+	sort.SliceStable(cs, func(i, j int) bool {
+		if cs[i].Breaking != cs[j].Breaking {
+			return cs[i].Breaking
+		}
+
+		return cs[i].Subject < cs[j].Subject
+	})
+
+	return cs
+}
+
+type changes []Change
+
+func (cs *changes) add(k Kind, subject, old, new string) {
+	*cs = append(*cs, Change{
+		Kind:     k,
+		Subject:  subject,
+		Breaking: breaking[k],
+		Old:      old,
+		New:      new,
+	})
+}
+
+func (cs *changes) methods(old, new []ir.Interface) {
+	newAPIs := make(map[string]map[string]ir.Method)
+	for _, i := range new {
+		newAPIs[i.Name] = methodsByName(i.Methods)
+	}
+
+	oldAPIs := make(map[string]map[string]ir.Method)
+	for _, i := range old {
+		oldAPIs[i.Name] = methodsByName(i.Methods)
+
+		for _, m := range i.Methods {
+			subject := i.Name + "." + m.Name
+			n, ok := newAPIs[i.Name][m.Name]
+			if !ok {
+				cs.add(MethodRemoved, subject, "", "")
+				continue
+			}
+
+			cs.fields(subject, m.Params, n.Params, paramKey, ParamAdded, ParamRemoved, ParamTypeChanged)
+			cs.fields(subject, m.Results, n.Results, paramKey, ResultAdded, ResultRemoved, ResultTypeChanged)
+		}
+	}
+
+	for _, i := range new {
+		for _, m := range i.Methods {
+			if _, ok := oldAPIs[i.Name][m.Name]; !ok {
+				cs.add(MethodAdded, i.Name+"."+m.Name, "", "")
+			}
+		}
+	}
+}
+
+func methodsByName(ms []ir.Method) map[string]ir.Method {
+	res := make(map[string]ir.Method)
+	for _, m := range ms {
+		res[m.Name] = m
+	}
+
+	return res
+}
+
+// paramKey keys parameters and results by name, which their keys in the
+// request and response bodies are named after.
+func paramKey(f ir.Field) (string, bool) {
+	return f.Name, true
+}
+
+// fieldKey keys struct fields by their JSON key, skipping fields that aren't
+// encoded, as renaming the key breaks clients while renaming the field doesn't.
+func fieldKey(f ir.Field) (string, bool) {
+	return f.JSONName()
+}
+
+// fields compares fields by their key, as they are encoded in JSON.
+func (cs *changes) fields(subject string, old, new []ir.Field, key func(ir.Field) (string, bool), added, removed, changed Kind) {
+	newFields := make(map[string]ir.Field)
+	for _, f := range new {
+		if k, ok := key(f); ok {
+			newFields[k] = f
+		}
+	}
+
+	oldFields := make(map[string]bool)
+	for _, f := range old {
+		k, ok := key(f)
+		if !ok {
+			continue
+		}
+		oldFields[k] = true
+
+		n, ok := newFields[k]
+		if !ok {
+			cs.add(removed, subject+"."+k, f.Type, "")
+		} else if f.Type != n.Type {
+			cs.add(changed, subject+"."+k, f.Type, n.Type)
+		}
+	}
+
+	for k, f := range newFields {
+		if !oldFields[k] {
+			cs.add(added, subject+"."+k, "", f.Type)
+		}
+	}
+}
+
+func (cs *changes) types(old, new []ir.Type) {
+	oldTypes := typesByName(old)
+	newTypes := typesByName(new)
+
+	for name, o := range oldTypes {
+		n, ok := newTypes[name]
+		if !ok {
+			cs.add(TypeRemoved, name, "", "")
+			continue
+		}
+
+		if o.Kind != n.Kind || o.Underlying != n.Underlying {
+			cs.add(TypeKindChanged, name, typeKind(o), typeKind(n))
+			continue
+		}
+
+		switch o.Kind {
+		case ir.KindStruct:
+			cs.fields(name, o.Fields, n.Fields, fieldKey, FieldAdded, FieldRemoved, FieldTypeChanged)
+		case ir.KindEnum:
+			cs.enumValues(name, o.Values, n.Values)
+		}
+	}
+
+	for name := range newTypes {
+		if _, ok := oldTypes[name]; !ok {
+			cs.add(TypeAdded, name, "", "")
+		}
+	}
+}
+
+// typesByName keys the types by package and name.
+func typesByName(ts []ir.Type) map[string]ir.Type {
+	res := make(map[string]ir.Type)
+	for _, t := range ts {
+		res[t.Package+"."+t.Name] = t
+	}
+
+	return res
+}
+
+func typeKind(t ir.Type) string {
+	if t.Kind == ir.KindEnum {
+		return t.Underlying
+	}
+
+	return string(t.Kind)
+}
+
+// enumValues compares the constants of an enum, which are encoded by value.
+func (cs *changes) enumValues(name string, old, new []ir.Value) {
+	oldValues := valuesByName(old)
+	newValues := valuesByName(new)
+
+	for c, v := range oldValues {
+		n, ok := newValues[c]
+		if !ok {
+			cs.add(EnumValueRemoved, name+"."+c, v, "")
+		} else if n != v {
+			cs.add(EnumValueChanged, name+"."+c, v, n)
+		}
+	}
+
+	for c, v := range newValues {
+		if _, ok := oldValues[c]; !ok {
+			cs.add(EnumValueAdded, name+"."+c, "", v)
+		}
+	}
+}
+
+func valuesByName(vs []ir.Value) map[string]string {
+	res := make(map[string]string)
+	for _, v := range vs {
+		res[v.Name] = v.Value
+	}
+
+	return res
+}
@@ -0,0 +1,76 @@
+package diff_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/luno/gobridge/diff"
+	"github.com/luno/gobridge/ir"
+)
+
+// userAPI returns an API with a User struct of the fields.
+func userAPI(fields ...ir.Field) *ir.API {
+	return &ir.API{
+		Types: []ir.Type{{Name: "User", Package: "backend", Kind: ir.KindStruct, Fields: fields}},
+	}
+}
+
+func TestCompareFields(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new []ir.Field
+		want     []diff.Change
+	}{
+		{
+			name: "renamed field keeping its tag",
+			old:  []ir.Field{{Name: "Name", Type: "string", Tag: `json:"name"`}},
+			new:  []ir.Field{{Name: "FullName", Type: "string", Tag: `json:"name"`}},
+		},
+		{
+			name: "renamed tag",
+			old:  []ir.Field{{Name: "Name", Type: "string", Tag: `json:"name"`}},
+			new:  []ir.Field{{Name: "Name", Type: "string", Tag: `json:"full_name,omitempty"`}},
+			want: []diff.Change{
+				{Kind: diff.FieldRemoved, Subject: "backend.User.name", Breaking: true, Old: "string"},
+				{Kind: diff.FieldAdded, Subject: "backend.User.full_name", New: "string"},
+			},
+		},
+		{
+			name: "tag added to a field",
+			old:  []ir.Field{{Name: "Name", Type: "string"}},
+			new:  []ir.Field{{Name: "Name", Type: "string", Tag: `json:"name"`}},
+			want: []diff.Change{
+				{Kind: diff.FieldRemoved, Subject: "backend.User.Name", Breaking: true, Old: "string"},
+				{Kind: diff.FieldAdded, Subject: "backend.User.name", New: "string"},
+			},
+		},
+		{
+			name: "retyped field",
+			old:  []ir.Field{{Name: "ID", Type: "int64", Tag: `json:"id"`}},
+			new:  []ir.Field{{Name: "ID", Type: "string", Tag: `json:"id"`}},
+			want: []diff.Change{
+				{Kind: diff.FieldTypeChanged, Subject: "backend.User.id", Breaking: true, Old: "int64", New: "string"},
+			},
+		},
+		{
+			name: "fields which aren't encoded",
+			old: []ir.Field{
+				{Name: "token", Type: "string"},
+				{Name: "Secret", Type: "string", Tag: `json:"-"`},
+			},
+			new: []ir.Field{
+				{Name: "Secret", Type: "[]byte", Tag: `json:"-"`},
+				{Name: "cache", Type: "map[string]string"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := diff.Compare(userAPI(test.old...), userAPI(test.new...))
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
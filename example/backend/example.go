@@ -2,12 +2,25 @@ package backend
 
 import (
 	"context"
+	"math/big"
 	"time"
 
 	"github.com/luno/gobridge/example/backend/second"
 )
 
+// Example is served by the example backend.
 type Example interface {
+	// HasPermission reports whether the user has any of the roles.
 	HasPermission(ctx context.Context, r []Role, u User, inventoryUpdate map[int64]bool) (bool, error)
+
+	// WhatsTheTime reports whether the toy was created before the date.
+	//
+	// Deprecated: compare the dates in the client instead.
+	//gobridge:timeout 5s
+	//gobridge:sunset 2027-01-31
 	WhatsTheTime(ctx context.Context, date time.Time, toy second.Toy) (bool, error)
+
+	// Transfer takes the cents from the balance of the user, returning the
+	// balance left and whether it is overdrawn.
+	Transfer(ctx context.Context, u User, cents *big.Int) (balance *big.Int, overdrawn bool, err error)
 }
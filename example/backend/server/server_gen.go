@@ -3,22 +3,46 @@
 package server
 
 import (
+	"bytes"
 	"context"
+	"encoding"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"io/ioutil"
+	"log/slog"
+	"math/big"
 	"net/http"
+	"reflect"
+	"runtime/debug"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/luno/gobridge/example/backend"
 	"github.com/luno/gobridge/example/backend/second"
 )
 
-func New(api backend.Example, a AuthConfig, basicAuth func(ctx context.Context, token string) (bool, error)) *Server {
+func New(api backend.Example, a AuthConfig, basicAuth func(ctx context.Context, token string) (bool, error), opts ...Option) *Server {
 	s := &Server{
 		AdditionalAuth: a,
 		Basic:          basicAuth,
 		API:            api,
+		Logger:         slog.Default(),
+		Tracer:         W3CTracer{},
+		MaxBodySize:    DefaultMaxBodySize,
+		Timeouts:       make(map[Endpoint]time.Duration),
+	}
+
+	for e, d := range declaredTimeouts {
+		s.Timeouts[e] = d
+	}
+
+	for _, o := range opts {
+		o(s)
 	}
 
 	s.registerHandlers()
@@ -26,12 +50,51 @@ func New(api backend.Example, a AuthConfig, basicAuth func(ctx context.Context,
 	return s
 }
 
+// Option configures optional behaviour of the Server.
+type Option func(s *Server)
+
+// WithLogger sets the logger used to emit one structured record per request.
+// Passing nil disables request logging.
+func WithLogger(l *slog.Logger) Option {
+	return func(s *Server) {
+		s.Logger = l
+	}
+}
+
+// DefaultMaxBodySize is the default limit on the size of request bodies.
+const DefaultMaxBodySize = 10 << 20
+
+// WithMaxBodySize limits the size of request bodies, responding with 413 when
+// exceeded. A size of zero or less removes the limit.
+func WithMaxBodySize(n int64) Option {
+	return func(s *Server) {
+		s.MaxBodySize = n
+	}
+}
+
+// WithTimeout sets the deadline of the context passed to the API method of an
+// endpoint, overriding any declared with //gobridge:timeout. AllEndpoints sets
+// the deadline for endpoints without their own. Requests that exceed their
+// deadline get a 504 response once the API method returns, whatever it returned.
+func WithTimeout(e Endpoint, d time.Duration) Option {
+	return func(s *Server) {
+		s.Timeouts[e] = d
+	}
+}
+
 type AuthConfig map[Endpoint]func(ctx context.Context, token string) (bool, error)
 
 type Server struct {
 	AdditionalAuth AuthConfig
 	Basic          func(ctx context.Context, token string) (bool, error)
 	API            backend.Example
+	Logger         *slog.Logger
+	Observers      []Observer
+	Metrics        *Metrics
+	Tracer         Tracer
+	MaxBodySize    int64
+	Timeouts       map[Endpoint]time.Duration
+	DecodeMode     DecodeMode
 }
 
 type Endpoint int
@@ -39,9 +102,26 @@ type Endpoint int
 var (
 	HasPermissionEndpoint Endpoint = 0
 	WhatsTheTimeEndpoint  Endpoint = 1
-	AllEndpoints          Endpoint = 2
+	TransferEndpoint      Endpoint = 2
+	AllEndpoints          Endpoint = 3
 )
 
+// declaredTimeouts are the deadlines declared with //gobridge:timeout on the API methods.
+var declaredTimeouts = map[Endpoint]time.Duration{
+	WhatsTheTimeEndpoint: 5 * time.Second,
+}
+
+// deprecation is the Deprecation and Sunset headers sent by a deprecated endpoint.
+type deprecation struct {
+	Deprecation string
+	Sunset      string
+}
+
+// deprecations are the endpoints of the methods documented as deprecated.
+var deprecations = map[Endpoint]deprecation{
+	WhatsTheTimeEndpoint: {Deprecation: "true", Sunset: "Sun, 31 Jan 2027 00:00:00 GMT"},
+}
+
 func (ep Endpoint) Path() string {
 	switch ep {
 	case AllEndpoints:
@@ -50,6 +130,8 @@ func (ep Endpoint) Path() string {
 		return "/backend/haspermission"
 	case WhatsTheTimeEndpoint:
 		return "/backend/whatsthetime"
+	case TransferEndpoint:
+		return "/backend/transfer"
 	default:
 		return ""
 	}
@@ -58,18 +140,85 @@ func (ep Endpoint) Path() string {
 func (s *Server) registerHandlers() {
 	http.HandleFunc("/backend/haspermission", s.Wrap(HasPermissionEndpoint, HandleHasPermission(s.API)))
 	http.HandleFunc("/backend/whatsthetime", s.Wrap(WhatsTheTimeEndpoint, HandleWhatsTheTime(s.API)))
+	http.HandleFunc("/backend/transfer", s.Wrap(TransferEndpoint, HandleTransfer(s.API)))
+
+	if s.Metrics != nil {
+		http.Handle("/metrics", s.Metrics)
+	}
 }
 
 func (s *Server) Wrap(e Endpoint, fn func(w http.ResponseWriter, r *http.Request)) func(w http.ResponseWriter, r *http.Request) {
-	return func(w http.ResponseWriter, r *http.Request) {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		w := &responseWriter{ResponseWriter: rw, status: http.StatusOK}
+		body := &countingReader{ReadCloser: r.Body}
+		if r.Body != nil {
+			r.Body = body
+		}
+
+		s.requestStarted(r.Context(), e)
+		defer func() {
+			p := recover()
+			if p != nil && p != http.ErrAbortHandler {
+				if w.wroteHeader {
+					// Too late to change the response, but make sure it is logged as a failure.
+					w.status = http.StatusInternalServerError
+				} else {
+					// The panic value can hold anything, so it's only logged.
+					http.Error(w, "internal server error", http.StatusInternalServerError)
+				}
+				w.err = fmt.Errorf("panic: %v", p)
+				w.stack = debug.Stack()
+			}
+
+			latency := time.Since(start)
+			s.logRequest(r, e, w, latency)
+
+			info := RequestInfo{
+				Status:        w.status,
+				Duration:      latency,
+				RequestBytes:  body.n,
+				ResponseBytes: w.bytes,
+			}
+			if w.status >= http.StatusBadRequest {
+				info.ErrorCode = http.StatusText(w.status)
+			}
+			s.requestFinished(r.Context(), e, info)
+
+			// http.ErrAbortHandler aborts the response, which the http.Server
+			// does when it recovers it.
+			if p == http.ErrAbortHandler {
+				panic(p)
+			}
+		}()
+
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Kind, Authorization")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Kind, Authorization, traceparent, tracestate")
 
 		if r.Method == http.MethodOptions {
 			w.WriteHeader(http.StatusOK)
 			return
 		}
 
+		if d, ok := deprecations[e]; ok {
+			w.Header().Set("Access-Control-Expose-Headers", "Deprecation, Sunset")
+			w.Header().Set("Deprecation", d.Deprecation)
+			if d.Sunset != "" {
+				w.Header().Set("Sunset", d.Sunset)
+			}
+			s.warnDeprecated(r, e, d)
+		}
+
+		if s.Tracer != nil {
+			r = r.WithContext(s.Tracer.Extract(r.Context(), TraceContextFromHeader(r.Header)))
+		}
+
+		if s.MaxBodySize > 0 && r.Body != nil {
+			r.Body = http.MaxBytesReader(w, r.Body, s.MaxBodySize)
+		}
+
+		r = r.WithContext(context.WithValue(r.Context(), decodeModeKey{}, s.DecodeMode))
+
 		allow, msg, reason := checkAuth(w, r, s.Basic)
 		if !allow {
 			http.Error(w, msg, reason)
@@ -97,10 +246,147 @@ func (s *Server) Wrap(e Endpoint, fn func(w http.ResponseWriter, r *http.Request
 			}
 		}
 
+		// The deadline starts once the request is authorised, so slow auth
+		// checks don't use up the time of the API call.
+		if d, ok := s.timeout(e); ok {
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+			r = r.WithContext(ctx)
+		}
+
 		fn(w, r)
 	}
 }
 
+// timeout returns the deadline of the endpoint, falling back to the one set
+// for AllEndpoints.
+func (s *Server) timeout(e Endpoint) (time.Duration, bool) {
+	if d, ok := s.Timeouts[e]; ok && d > 0 {
+		return d, true
+	}
+
+	d, ok := s.Timeouts[AllEndpoints]
+	return d, ok && d > 0
+}
+
+// warnDeprecated logs a call to a deprecated endpoint, so that the consumers
+// still calling it can be found before it's removed.
+func (s *Server) warnDeprecated(r *http.Request, e Endpoint, d deprecation) {
+	if s.Logger == nil {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("endpoint", e.Path()),
+		slog.String("user_agent", r.UserAgent()),
+	}
+	if d.Sunset != "" {
+		attrs = append(attrs, slog.String("sunset", d.Sunset))
+	}
+
+	s.Logger.LogAttrs(r.Context(), slog.LevelWarn, "gobridge deprecated endpoint called", attrs...)
+}
+
+func (s *Server) logRequest(r *http.Request, e Endpoint, w *responseWriter, latency time.Duration) {
+	if s.Logger == nil {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("endpoint", e.Path()),
+		slog.Int("status", w.status),
+		slog.Duration("latency", latency),
+	}
+
+	level := slog.LevelInfo
+	if w.status >= http.StatusBadRequest {
+		level = slog.LevelWarn
+	}
+	if w.status >= http.StatusInternalServerError {
+		level = slog.LevelError
+	}
+
+	if w.err != nil {
+		attrs = append(attrs, slog.String("error", w.err.Error()))
+	}
+	if w.stack != nil {
+		attrs = append(attrs, slog.String("stack", string(w.stack)))
+	}
+
+	s.Logger.LogAttrs(r.Context(), level, "gobridge request", attrs...)
+}
+
+// responseWriter records the status code, size and error of a response so
+// that it can be logged and observed once the request has completed.
+type responseWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	bytes       int64
+	err         error
+	stack       []byte // Stack trace of a recovered panic
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+
+	w.status = status
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// writeError responds with the status and error message, recording the error
+// for the request log when the writer was created by Server.Wrap. A
+// DecodeError is written as JSON.
+func writeError(w http.ResponseWriter, status int, err error) {
+	if rw, ok := w.(*responseWriter); ok {
+		rw.err = err
+	}
+
+	var decodeErr *DecodeError
+	if errors.As(err, &decodeErr) {
+		b, _ := json.Marshal(decodeErr)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = w.Write(b)
+		return
+	}
+
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(err.Error()))
+}
+
+// bodyErrorStatus returns the status for a failure to read the request body.
+func bodyErrorStatus(err error) int {
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		return http.StatusRequestEntityTooLarge
+	}
+
+	return http.StatusBadRequest
+}
+
+// apiErrorStatus returns the status for an error returned by an API method.
+func apiErrorStatus(err error) int {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return http.StatusGatewayTimeout
+	}
+
+	return http.StatusInternalServerError
+}
+
 func checkAuth(w http.ResponseWriter, r *http.Request, authFunc func(ctx context.Context, token string) (bool, error)) (bool, string, int) {
 	t := strings.TrimSpace(r.Header.Get("Authorization"))
 	allow, err := authFunc(r.Context(), t)
@@ -116,50 +402,655 @@ func checkAuth(w http.ResponseWriter, r *http.Request, authFunc func(ctx context
 	return true, "", http.StatusOK
 }
 
+// Observer is notified at the start and end of every request handled by the Server.
+type Observer interface {
+	RequestStarted(ctx context.Context, e Endpoint)
+	RequestFinished(ctx context.Context, e Endpoint, info RequestInfo)
+}
+
+// RequestInfo describes a finished request.
+type RequestInfo struct {
+	Status        int
+	Duration      time.Duration
+	RequestBytes  int64
+	ResponseBytes int64
+	ErrorCode     string // Empty for successful requests, otherwise the HTTP status text
+}
+
+// WithObserver adds an Observer that is notified of every request.
+func WithObserver(o Observer) Option {
+	return func(s *Server) {
+		s.Observers = append(s.Observers, o)
+	}
+}
+
+// WithMetrics records request metrics in m and serves them in the Prometheus
+// text format on /metrics. The metrics handler is not wrapped by the auth checks.
+func WithMetrics(m *Metrics) Option {
+	return func(s *Server) {
+		s.Observers = append(s.Observers, m)
+		s.Metrics = m
+	}
+}
+
+func (s *Server) requestStarted(ctx context.Context, e Endpoint) {
+	for _, o := range s.Observers {
+		o.RequestStarted(ctx, e)
+	}
+}
+
+func (s *Server) requestFinished(ctx context.Context, e Endpoint, info RequestInfo) {
+	for _, o := range s.Observers {
+		o.RequestFinished(ctx, e, info)
+	}
+}
+
+// countingReader counts the bytes read from a request body.
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (r *countingReader) Read(b []byte) (int, error) {
+	n, err := r.ReadCloser.Read(b)
+	r.n += int64(n)
+	return n, err
+}
+
+// DurationBuckets are the upper bounds, in seconds, of the request duration histogram.
+var DurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Metrics is an Observer that aggregates per endpoint request metrics and
+// exposes them in the Prometheus text exposition format.
+type Metrics struct {
+	mu            sync.Mutex
+	inFlight      map[Endpoint]int64
+	requests      map[metricsKey]int64
+	durations     map[Endpoint]*histogram
+	requestBytes  map[Endpoint]int64
+	responseBytes map[Endpoint]int64
+}
+
+type metricsKey struct {
+	endpoint Endpoint
+	status   int
+}
+
+type histogram struct {
+	buckets []uint64 // Cumulative counts for each of the DurationBuckets
+	sum     float64
+	count   uint64
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{
+		inFlight:      make(map[Endpoint]int64),
+		requests:      make(map[metricsKey]int64),
+		durations:     make(map[Endpoint]*histogram),
+		requestBytes:  make(map[Endpoint]int64),
+		responseBytes: make(map[Endpoint]int64),
+	}
+}
+
+func (m *Metrics) RequestStarted(_ context.Context, e Endpoint) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.inFlight[e]++
+}
+
+func (m *Metrics) RequestFinished(_ context.Context, e Endpoint, info RequestInfo) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.inFlight[e]--
+	m.requests[metricsKey{endpoint: e, status: info.Status}]++
+	m.requestBytes[e] += info.RequestBytes
+	m.responseBytes[e] += info.ResponseBytes
+
+	h, ok := m.durations[e]
+	if !ok {
+		h = &histogram{buckets: make([]uint64, len(DurationBuckets))}
+		m.durations[e] = h
+	}
+
+	secs := info.Duration.Seconds()
+	for i, bound := range DurationBuckets {
+		if secs <= bound {
+			h.buckets[i]++
+		}
+	}
+	h.sum += secs
+	h.count++
+}
+
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var buf bytes.Buffer
+
+	buf.WriteString("# HELP gobridge_requests_total Total number of requests handled per endpoint and status.\n")
+	buf.WriteString("# TYPE gobridge_requests_total counter\n")
+	keys := make([]metricsKey, 0, len(m.requests))
+	for k := range m.requests {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].endpoint != keys[j].endpoint {
+			return keys[i].endpoint < keys[j].endpoint
+		}
+		return keys[i].status < keys[j].status
+	})
+	for _, k := range keys {
+		fmt.Fprintf(&buf, "gobridge_requests_total{endpoint=%q,status=\"%d\"} %d\n", k.endpoint.Path(), k.status, m.requests[k])
+	}
+
+	buf.WriteString("# HELP gobridge_requests_in_flight Number of requests currently being handled per endpoint.\n")
+	buf.WriteString("# TYPE gobridge_requests_in_flight gauge\n")
+	for e := Endpoint(0); e < AllEndpoints; e++ {
+		fmt.Fprintf(&buf, "gobridge_requests_in_flight{endpoint=%q} %d\n", e.Path(), m.inFlight[e])
+	}
+
+	buf.WriteString("# HELP gobridge_request_duration_seconds Time taken to handle requests per endpoint.\n")
+	buf.WriteString("# TYPE gobridge_request_duration_seconds histogram\n")
+	for e := Endpoint(0); e < AllEndpoints; e++ {
+		h, ok := m.durations[e]
+		if !ok {
+			continue
+		}
+
+		for i, bound := range DurationBuckets {
+			le := strconv.FormatFloat(bound, 'g', -1, 64)
+			fmt.Fprintf(&buf, "gobridge_request_duration_seconds_bucket{endpoint=%q,le=%q} %d\n", e.Path(), le, h.buckets[i])
+		}
+		fmt.Fprintf(&buf, "gobridge_request_duration_seconds_bucket{endpoint=%q,le=\"+Inf\"} %d\n", e.Path(), h.count)
+		fmt.Fprintf(&buf, "gobridge_request_duration_seconds_sum{endpoint=%q} %g\n", e.Path(), h.sum)
+		fmt.Fprintf(&buf, "gobridge_request_duration_seconds_count{endpoint=%q} %d\n", e.Path(), h.count)
+	}
+
+	buf.WriteString("# HELP gobridge_request_size_bytes_total Total size of request bodies per endpoint.\n")
+	buf.WriteString("# TYPE gobridge_request_size_bytes_total counter\n")
+	for e := Endpoint(0); e < AllEndpoints; e++ {
+		fmt.Fprintf(&buf, "gobridge_request_size_bytes_total{endpoint=%q} %d\n", e.Path(), m.requestBytes[e])
+	}
+
+	buf.WriteString("# HELP gobridge_response_size_bytes_total Total size of response bodies per endpoint.\n")
+	buf.WriteString("# TYPE gobridge_response_size_bytes_total counter\n")
+	for e := Endpoint(0); e < AllEndpoints; e++ {
+		fmt.Fprintf(&buf, "gobridge_response_size_bytes_total{endpoint=%q} %d\n", e.Path(), m.responseBytes[e])
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = w.Write(buf.Bytes())
+}
+
+// TraceContext holds the W3C trace context propagated with a request.
+type TraceContext struct {
+	TraceParent string
+	TraceState  string
+}
+
+// TraceContextFromHeader reads the traceparent and tracestate headers. An
+// invalid traceparent is discarded together with the tracestate.
+func TraceContextFromHeader(h http.Header) TraceContext {
+	tc := TraceContext{
+		TraceParent: strings.TrimSpace(h.Get("traceparent")),
+		TraceState:  strings.TrimSpace(h.Get("tracestate")),
+	}
+
+	if !validTraceParent(tc.TraceParent) {
+		return TraceContext{}
+	}
+
+	return tc
+}
+
+// SetHeader writes the trace context to the traceparent and tracestate headers.
+func (tc TraceContext) SetHeader(h http.Header) {
+	if tc.TraceParent == "" {
+		return
+	}
+
+	h.Set("traceparent", tc.TraceParent)
+	if tc.TraceState != "" {
+		h.Set("tracestate", tc.TraceState)
+	}
+}
+
+// Tracer bridges the generated server and client to a tracing library.
+type Tracer interface {
+	// Extract returns a context carrying the trace context received with an
+	// incoming request. It is called before the API method.
+	Extract(ctx context.Context, tc TraceContext) context.Context
+
+	// Inject returns the trace context of ctx to send with an outgoing request.
+	Inject(ctx context.Context) TraceContext
+}
+
+// WithTracer sets the Tracer used to extract the trace context of incoming requests.
+func WithTracer(t Tracer) Option {
+	return func(s *Server) {
+		s.Tracer = t
+	}
+}
+
+// W3CTracer is the default Tracer. It stores the incoming trace context in
+// the request context unchanged so that it flows through to outgoing client
+// calls without requiring a tracing library.
+type W3CTracer struct{}
+
+func (W3CTracer) Extract(ctx context.Context, tc TraceContext) context.Context {
+	if tc.TraceParent == "" {
+		return ctx
+	}
+
+	return ContextWithTraceContext(ctx, tc)
+}
+
+func (W3CTracer) Inject(ctx context.Context) TraceContext {
+	return TraceContextFromContext(ctx)
+}
+
+type traceContextKey struct{}
+
+// ContextWithTraceContext returns a copy of ctx carrying tc.
+func ContextWithTraceContext(ctx context.Context, tc TraceContext) context.Context {
+	return context.WithValue(ctx, traceContextKey{}, tc)
+}
+
+// TraceContextFromContext returns the trace context stored in ctx, if any.
+func TraceContextFromContext(ctx context.Context) TraceContext {
+	tc, _ := ctx.Value(traceContextKey{}).(TraceContext)
+	return tc
+}
+
+// validTraceParent checks the version-traceid-parentid-flags format of a
+// traceparent header, e.g. 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+func validTraceParent(tp string) bool {
+	parts := strings.Split(tp, "-")
+	if len(parts) < 4 {
+		return false
+	}
+
+	// Future versions may append fields, version 00 may not
+	if parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return false
+	}
+
+	for i, l := range []int{2, 32, 16, 2} {
+		if len(parts[i]) != l {
+			return false
+		}
+
+		allZero := true
+		for _, c := range parts[i] {
+			if !(c >= '0' && c <= '9') && !(c >= 'a' && c <= 'f') {
+				return false
+			}
+			if c != '0' {
+				allZero = false
+			}
+		}
+
+		// Trace and parent IDs of all zeros are invalid
+		if allZero && (i == 1 || i == 2) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// DecodeMode controls how request bodies are decoded.
+type DecodeMode int
+
+const (
+	// DecodeLenient ignores unknown fields, matching json.Unmarshal.
+	DecodeLenient DecodeMode = 0
+
+	// DecodeStrict rejects unknown fields and trailing data, responding with
+	// a DecodeError listing the offending fields.
+	DecodeStrict DecodeMode = 1
+)
+
+// WithDecodeMode sets how request bodies are decoded. The default is DecodeLenient.
+func WithDecodeMode(m DecodeMode) Option {
+	return func(s *Server) {
+		s.DecodeMode = m
+	}
+}
+
+type decodeModeKey struct{}
+
+// DecodeError is the JSON body of a 400 response to a request that could not
+// be decoded in strict mode.
+type DecodeError struct {
+	Message string   `json:"message"`
+	Fields  []string `json:"fields,omitempty"`
+}
+
+func (e *DecodeError) Error() string {
+	if len(e.Fields) == 0 {
+		return e.Message
+	}
+
+	return e.Message + ": " + strings.Join(e.Fields, ", ")
+}
+
+// decodeRequest decodes the request body into v using the DecodeMode set on
+// the context by Server.Wrap.
+func decodeRequest(ctx context.Context, b []byte, v interface{}) error {
+	if int64AsString {
+		b = convertInt64(b, reflect.TypeOf(v), false)
+	}
+
+	mode, _ := ctx.Value(decodeModeKey{}).(DecodeMode)
+	if mode != DecodeStrict {
+		return json.Unmarshal(b, v)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+
+	err := dec.Decode(v)
+	if err != nil {
+		return newDecodeError(b, v, err)
+	}
+
+	if _, err := dec.Token(); err != io.EOF {
+		return &DecodeError{Message: "unexpected data after request body"}
+	}
+
+	return nil
+}
+
+func newDecodeError(b []byte, v interface{}, err error) *DecodeError {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return &DecodeError{
+			Message: fmt.Sprintf("invalid value: expected %s but got %s", typeErr.Type, typeErr.Value),
+			Fields:  []string{typeErr.Field},
+		}
+	}
+
+	if strings.HasPrefix(err.Error(), "json: unknown field ") {
+		var raw interface{}
+		if json.Unmarshal(b, &raw) == nil {
+			fields := unknownFields(reflect.TypeOf(v), raw, "")
+			if len(fields) > 0 {
+				return &DecodeError{Message: "unknown fields", Fields: fields}
+			}
+		}
+
+		name := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return &DecodeError{Message: "unknown fields", Fields: []string{name}}
+	}
+
+	return &DecodeError{Message: err.Error()}
+}
+
+var unmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// unknownFields returns the paths of all the object keys in raw that don't
+// match a field of t.
+func unknownFields(t reflect.Type, raw interface{}, path string) []string {
+	if reflect.PointerTo(t).Implements(unmarshalerType) {
+		return nil
+	}
+
+	var res []string
+	switch t.Kind() {
+	case reflect.Pointer:
+		return unknownFields(t.Elem(), raw, path)
+	case reflect.Slice, reflect.Array:
+		l, _ := raw.([]interface{})
+		for i, v := range l {
+			res = append(res, unknownFields(t.Elem(), v, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	case reflect.Map:
+		m, _ := raw.(map[string]interface{})
+		for k, v := range m {
+			res = append(res, unknownFields(t.Elem(), v, fmt.Sprintf("%s[%s]", path, k))...)
+		}
+	case reflect.Struct:
+		m, _ := raw.(map[string]interface{})
+		fields := jsonFields(t)
+		for k, v := range m {
+			p := k
+			if path != "" {
+				p = path + "." + k
+			}
+
+			f, ok := fields[strings.ToLower(k)]
+			if !ok {
+				res = append(res, p)
+				continue
+			}
+
+			res = append(res, unknownFields(f.typ, v, p)...)
+		}
+	}
+
+	sort.Strings(res)
+	return res
+}
+
+type jsonField struct {
+	typ    reflect.Type
+	quoted bool // Set by the ",string" tag option
+}
+
+// jsonFields returns the fields of struct t keyed by their lowercased JSON names.
+func jsonFields(t reflect.Type) map[string]jsonField {
+	fields := make(map[string]jsonField)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				for n, typ := range jsonFields(ft) {
+					fields[n] = typ
+				}
+				continue
+			}
+		}
+
+		if !f.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+		fields[strings.ToLower(name)] = jsonField{typ: f.Type, quoted: strings.Contains(","+opts+",", ",string,")}
+	}
+
+	return fields
+}
+
+// int64AsString is set when the API was generated with int64 and uint64
+// values encoded as JSON strings, as JavaScript numbers can't represent them
+// exactly above 2^53.
+const int64AsString = false
+
+// EncodeJSON encodes v the way the server encodes responses.
+func EncodeJSON(v interface{}) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil || !int64AsString {
+		return b, err
+	}
+
+	return convertInt64(b, reflect.TypeOf(v), true), nil
+}
+
+// DecodeJSON decodes JSON produced by EncodeJSON into v.
+func DecodeJSON(b []byte, v interface{}) error {
+	if int64AsString {
+		b = convertInt64(b, reflect.TypeOf(v), false)
+	}
+
+	return json.Unmarshal(b, v)
+}
+
+var (
+	durationType      = reflect.TypeOf(time.Duration(0))
+	marshalerType     = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// isInt64 reports whether t is encoded as a 64-bit integer, including named
+// types like "type UserID int64". time.Duration is left as a number for the
+// clients to convert, and types with their own encoding are left alone.
+func isInt64(t reflect.Type) bool {
+	if t.Kind() != reflect.Int64 && t.Kind() != reflect.Uint64 {
+		return false
+	}
+
+	if t == durationType {
+		return false
+	}
+
+	for _, m := range []reflect.Type{marshalerType, textMarshalerType} {
+		if t.Implements(m) || reflect.PointerTo(t).Implements(m) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// convertInt64 rewrites the int64 and uint64 values of the JSON encoding of
+// t to strings, or back to numbers when toString is false. Invalid input is
+// returned unchanged for the decoder to report.
+func convertInt64(b []byte, t reflect.Type, toString bool) []byte {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+
+	var raw interface{}
+	if err := dec.Decode(&raw); err != nil {
+		return b
+	}
+
+	if _, err := dec.Token(); err != io.EOF {
+		return b
+	}
+
+	res, err := json.Marshal(walkInt64(t, raw, toString))
+	if err != nil {
+		return b
+	}
+
+	return res
+}
+
+func walkInt64(t reflect.Type, raw interface{}, toString bool) interface{} {
+	if t == nil || reflect.PointerTo(t).Implements(unmarshalerType) {
+		return raw
+	}
+
+	if isInt64(t) {
+		switch v := raw.(type) {
+		case json.Number:
+			if toString {
+				return v.String()
+			}
+		case string:
+			if !toString {
+				if _, err := strconv.ParseInt(v, 10, 64); err == nil {
+					return json.Number(v)
+				}
+				if _, err := strconv.ParseUint(v, 10, 64); err == nil {
+					return json.Number(v)
+				}
+			}
+		}
+
+		return raw
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return walkInt64(t.Elem(), raw, toString)
+	case reflect.Slice, reflect.Array:
+		l, _ := raw.([]interface{})
+		for i, v := range l {
+			l[i] = walkInt64(t.Elem(), v, toString)
+		}
+	case reflect.Map:
+		m, _ := raw.(map[string]interface{})
+		for k, v := range m {
+			m[k] = walkInt64(t.Elem(), v, toString)
+		}
+	case reflect.Struct:
+		m, _ := raw.(map[string]interface{})
+		fields := jsonFields(t)
+		for k, v := range m {
+			f, ok := fields[strings.ToLower(k)]
+			if !ok || f.quoted {
+				continue
+			}
+
+			m[k] = walkInt64(f.typ, v, toString)
+		}
+	}
+
+	return raw
+}
+
+// HasPermissionRequest is the request body of HasPermission.
 type HasPermissionRequest struct {
 	R               []backend.Role
 	U               backend.User
 	InventoryUpdate map[int64]bool
 }
 
+// HasPermissionResponse is the response body of HasPermission.
 type HasPermissionResponse struct {
 	Bool bool
 }
 
-func HandleHasPermission(api backend.Example) func(http.ResponseWriter, *http.Request) {
+func HandleHasPermission(impl backend.Example) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		b, err := ioutil.ReadAll(r.Body)
 		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			_, _ = w.Write([]byte(err.Error()))
+			writeError(w, bodyErrorStatus(err), err)
 			return
 		}
 
 		var req HasPermissionRequest
-		err = json.Unmarshal(b, &req)
+		err = decodeRequest(r.Context(), b, &req)
 		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			_, _ = w.Write([]byte(err.Error()))
+			writeError(w, http.StatusBadRequest, err)
 			return
 		}
 
 		t := strings.TrimSpace(r.Header.Get("Authorization"))
 		ctx := context.WithValue(r.Context(), "authorization_header", t)
 
-		uqid, err := api.HasPermission(ctx, req.R, req.U, req.InventoryUpdate)
+		var resp HasPermissionResponse
+		resp.Bool, err = impl.HasPermission(ctx, req.R, req.U, req.InventoryUpdate)
+		if ctx.Err() == context.DeadlineExceeded {
+			// The client has given up on a late response, even a successful one.
+			writeError(w, http.StatusGatewayTimeout, ctx.Err())
+			return
+		}
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			_, _ = w.Write([]byte(err.Error()))
+			writeError(w, apiErrorStatus(err), err)
 			return
 		}
 
-		var resp HasPermissionResponse
-		resp.Bool, _ = uqid, err
-
-		respBody, err := json.Marshal(resp)
+		respBody, err := EncodeJSON(resp)
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			_, _ = w.Write([]byte(err.Error()))
+			writeError(w, http.StatusInternalServerError, err)
 			return
 		}
 
@@ -172,51 +1063,109 @@ func HandleHasPermission(api backend.Example) func(http.ResponseWriter, *http.Re
 	}
 }
 
+// WhatsTheTimeRequest is the request body of WhatsTheTime.
 type WhatsTheTimeRequest struct {
 	Date time.Time
 	Toy  second.Toy
 }
 
+// WhatsTheTimeResponse is the response body of WhatsTheTime.
 type WhatsTheTimeResponse struct {
 	Bool bool
 }
 
-func HandleWhatsTheTime(api backend.Example) func(http.ResponseWriter, *http.Request) {
+func HandleWhatsTheTime(impl backend.Example) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		b, err := ioutil.ReadAll(r.Body)
 		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			_, _ = w.Write([]byte(err.Error()))
+			writeError(w, bodyErrorStatus(err), err)
 			return
 		}
 
 		var req WhatsTheTimeRequest
-		err = json.Unmarshal(b, &req)
+		err = decodeRequest(r.Context(), b, &req)
 		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			_, _ = w.Write([]byte(err.Error()))
+			writeError(w, http.StatusBadRequest, err)
 			return
 		}
 
 		t := strings.TrimSpace(r.Header.Get("Authorization"))
 		ctx := context.WithValue(r.Context(), "authorization_header", t)
 
-		epfq, err := api.WhatsTheTime(ctx, req.Date, req.Toy)
+		var resp WhatsTheTimeResponse
+		resp.Bool, err = impl.WhatsTheTime(ctx, req.Date, req.Toy)
+		if ctx.Err() == context.DeadlineExceeded {
+			// The client has given up on a late response, even a successful one.
+			writeError(w, http.StatusGatewayTimeout, ctx.Err())
+			return
+		}
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			_, _ = w.Write([]byte(err.Error()))
+			writeError(w, apiErrorStatus(err), err)
 			return
 		}
 
-		var resp WhatsTheTimeResponse
-		resp.Bool, _ = epfq, err
+		respBody, err := EncodeJSON(resp)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
 
-		respBody, err := json.Marshal(resp)
+		w.WriteHeader(http.StatusOK)
+		_, err = w.Write(respBody)
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
 			_, _ = w.Write([]byte(err.Error()))
 			return
 		}
+	}
+}
+
+// TransferRequest is the request body of Transfer.
+type TransferRequest struct {
+	U     backend.User
+	Cents *big.Int
+}
+
+// TransferResponse is the response body of Transfer.
+type TransferResponse struct {
+	Balance   *big.Int
+	Overdrawn bool
+}
+
+func HandleTransfer(impl backend.Example) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			writeError(w, bodyErrorStatus(err), err)
+			return
+		}
+
+		var req TransferRequest
+		err = decodeRequest(r.Context(), b, &req)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		t := strings.TrimSpace(r.Header.Get("Authorization"))
+		ctx := context.WithValue(r.Context(), "authorization_header", t)
+
+		var resp TransferResponse
+		resp.Balance, resp.Overdrawn, err = impl.Transfer(ctx, req.U, req.Cents)
+		if ctx.Err() == context.DeadlineExceeded {
+			// The client has given up on a late response, even a successful one.
+			writeError(w, http.StatusGatewayTimeout, ctx.Err())
+			return
+		}
+		if err != nil {
+			writeError(w, apiErrorStatus(err), err)
+			return
+		}
+
+		respBody, err := EncodeJSON(resp)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
 
 		w.WriteHeader(http.StatusOK)
 		_, err = w.Write(respBody)
@@ -0,0 +1,285 @@
+package server_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/luno/gobridge/example/backend"
+	"github.com/luno/gobridge/example/backend/client"
+	"github.com/luno/gobridge/example/backend/second"
+	"github.com/luno/gobridge/example/backend/server"
+)
+
+// serve serves the handler of an endpoint wrapped like New does, without
+// registering it on http.DefaultServeMux. It returns the URL of the endpoint
+// and the request log.
+func serve(t *testing.T, e server.Endpoint, h func(http.ResponseWriter, *http.Request), opts ...server.Option) (string, *logBuffer) {
+	t.Helper()
+
+	logs := &logBuffer{}
+	s := &server.Server{
+		Basic:       func(context.Context, string) (bool, error) { return true, nil },
+		Logger:      slog.New(slog.NewJSONHandler(logs, nil)),
+		MaxBodySize: server.DefaultMaxBodySize,
+		Timeouts:    make(map[server.Endpoint]time.Duration),
+	}
+	for _, o := range opts {
+		o(s)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(e.Path(), s.Wrap(e, h))
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	return srv.URL + e.Path(), logs
+}
+
+// post sends body to url, returning the status and body of the response.
+func post(t *testing.T, url, body string) (int, string) {
+	t.Helper()
+
+	resp, err := http.Post(url, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return resp.StatusCode, string(b)
+}
+
+// logBuffer collects log output written by the handlers.
+type logBuffer struct {
+	mu  sync.Mutex
+	buf strings.Builder
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *logBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.String()
+}
+
+// hasPermissionFunc is an API whose HasPermission calls the func.
+type hasPermissionFunc func(ctx context.Context) (bool, error)
+
+func (f hasPermissionFunc) HasPermission(ctx context.Context, r []backend.Role, u backend.User, inventoryUpdate map[int64]bool) (bool, error) {
+	return f(ctx)
+}
+
+func (f hasPermissionFunc) WhatsTheTime(ctx context.Context, date time.Time, toy second.Toy) (bool, error) {
+	return false, nil
+}
+
+func (f hasPermissionFunc) Transfer(ctx context.Context, u backend.User, cents *big.Int) (*big.Int, bool, error) {
+	return nil, false, nil
+}
+
+// bank is an API whose Transfer takes the cents from a balance of 10^30.
+type bank struct {
+	hasPermissionFunc
+}
+
+func (bank) Transfer(ctx context.Context, u backend.User, cents *big.Int) (*big.Int, bool, error) {
+	balance := new(big.Int).Exp(big.NewInt(10), big.NewInt(30), nil)
+	balance.Sub(balance, cents)
+	return balance, balance.Sign() < 0, nil
+}
+
+func TestPanic(t *testing.T) {
+	api := hasPermissionFunc(func(context.Context) (bool, error) {
+		panic("secret value")
+	})
+	url, logs := serve(t, server.HasPermissionEndpoint, server.HandleHasPermission(api))
+
+	status, body := post(t, url, `{}`)
+	if status != http.StatusInternalServerError {
+		t.Errorf("status = %d, want %d", status, http.StatusInternalServerError)
+	}
+	if strings.Contains(body, "secret") || strings.Contains(body, "panic") {
+		t.Errorf("body %q exposes the panic", body)
+	}
+
+	log := logs.String()
+	if !strings.Contains(log, "panic: secret value") {
+		t.Errorf("log %q doesn't have the panic", log)
+	}
+	if !strings.Contains(log, `"stack":"goroutine `) {
+		t.Errorf("log %q doesn't have the stack", log)
+	}
+}
+
+func TestPanicAbortHandler(t *testing.T) {
+	api := hasPermissionFunc(func(context.Context) (bool, error) {
+		panic(http.ErrAbortHandler)
+	})
+	url, _ := serve(t, server.HasPermissionEndpoint, server.HandleHasPermission(api))
+
+	resp, err := http.Post(url, "application/json", strings.NewReader(`{}`))
+	if err == nil {
+		resp.Body.Close()
+		t.Fatalf("got a %d response, want the response aborted", resp.StatusCode)
+	}
+}
+
+func TestBodyTooLarge(t *testing.T) {
+	api := hasPermissionFunc(func(context.Context) (bool, error) {
+		return true, nil
+	})
+	url, _ := serve(t, server.HasPermissionEndpoint, server.HandleHasPermission(api), server.WithMaxBodySize(16))
+
+	status, body := post(t, url, `{"U": {"Name": "a name longer than the limit"}}`)
+	if status != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d, want %d", status, http.StatusRequestEntityTooLarge)
+	}
+	if !strings.Contains(body, "request body too large") {
+		t.Errorf("body = %q, want the size error", body)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	api := hasPermissionFunc(func(context.Context) (bool, error) {
+		// Ignores the deadline and succeeds late
+		time.Sleep(50 * time.Millisecond)
+		return true, nil
+	})
+	url, _ := serve(t, server.HasPermissionEndpoint, server.HandleHasPermission(api), server.WithTimeout(server.HasPermissionEndpoint, 10*time.Millisecond))
+
+	status, body := post(t, url, `{}`)
+	if status != http.StatusGatewayTimeout {
+		t.Errorf("status = %d, want %d", status, http.StatusGatewayTimeout)
+	}
+	if !strings.Contains(body, "deadline exceeded") {
+		t.Errorf("body = %q, want the deadline error", body)
+	}
+}
+
+func TestTimeoutAfterAuth(t *testing.T) {
+	api := hasPermissionFunc(func(ctx context.Context) (bool, error) {
+		return true, ctx.Err()
+	})
+	slowAuth := func(s *server.Server) {
+		s.Basic = func(context.Context, string) (bool, error) {
+			time.Sleep(50 * time.Millisecond)
+			return true, nil
+		}
+	}
+	url, _ := serve(t, server.HasPermissionEndpoint, server.HandleHasPermission(api), slowAuth, server.WithTimeout(server.HasPermissionEndpoint, 10*time.Millisecond))
+
+	// The deadline doesn't include the time spent authorising the request
+	status, body := post(t, url, `{}`)
+	if status != http.StatusOK {
+		t.Errorf("status = %d, want %d: %s", status, http.StatusOK, body)
+	}
+}
+
+func TestBigInt(t *testing.T) {
+	url, _ := serve(t, server.TransferEndpoint, server.HandleTransfer(bank{}))
+
+	// big.Int is encoded as a JSON number, as the TypeScript and OpenAPI
+	// types describe it
+	status, body := post(t, url, `{"Cents": 1}`)
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, want %d", status, http.StatusOK)
+	}
+	if want := `{"Balance":999999999999999999999999999999,"Overdrawn":false}`; body != want {
+		t.Errorf("body = %s, want %s", body, want)
+	}
+
+	// Values beyond int64 survive the round trip through the client
+	c := client.NewClient(strings.TrimSuffix(url, server.TransferEndpoint.Path()))
+	cents, _ := new(big.Int).SetString("1000000000000000000000000000001", 10)
+	balance, overdrawn, err := c.Transfer(context.Background(), backend.User{}, cents)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance.String() != "-1" || !overdrawn {
+		t.Errorf("Transfer = %v, %v, want -1, true", balance, overdrawn)
+	}
+}
+
+func TestDecodeStrict(t *testing.T) {
+	api := hasPermissionFunc(func(context.Context) (bool, error) {
+		return true, nil
+	})
+
+	tests := []struct {
+		name   string
+		mode   server.DecodeMode
+		body   string
+		status int
+		resp   string
+	}{
+		{
+			name:   "lenient ignores unknown fields",
+			mode:   server.DecodeLenient,
+			body:   `{"Extra": 1, "U": {"Nickname": "x"}}`,
+			status: http.StatusOK,
+			resp:   `{"Bool":true}`,
+		},
+		{
+			name:   "known fields",
+			mode:   server.DecodeStrict,
+			body:   `{"R": [1], "U": {"Name": "x"}}`,
+			status: http.StatusOK,
+			resp:   `{"Bool":true}`,
+		},
+		{
+			name:   "unknown fields",
+			mode:   server.DecodeStrict,
+			body:   `{"Extra": 1, "U": {"Nickname": "x"}}`,
+			status: http.StatusBadRequest,
+			resp:   `{"message":"unknown fields","fields":["Extra","U.Nickname"]}`,
+		},
+		{
+			name:   "wrong type",
+			mode:   server.DecodeStrict,
+			body:   `{"R": "admin"}`,
+			status: http.StatusBadRequest,
+			resp:   `{"message":"invalid value: expected []backend.Role but got string","fields":["R"]}`,
+		},
+		{
+			name:   "trailing data",
+			mode:   server.DecodeStrict,
+			body:   `{} {}`,
+			status: http.StatusBadRequest,
+			resp:   `{"message":"unexpected data after request body"}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			url, _ := serve(t, server.HasPermissionEndpoint, server.HandleHasPermission(api), server.WithDecodeMode(test.mode))
+
+			status, body := post(t, url, test.body)
+			if status != test.status {
+				t.Errorf("status = %d, want %d", status, test.status)
+			}
+			if body != test.resp {
+				t.Errorf("body = %s, want %s", body, test.resp)
+			}
+		})
+	}
+}
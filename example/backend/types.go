@@ -4,17 +4,19 @@ import (
 	"github.com/luno/gobridge/example/backend/second"
 )
 
+// User is a user of the example backend.
 type User struct {
 	ID   int64
-	Name string
+	Name string // Display name
 	Role Role
 	t    second.Toy
 }
 
+// Role is the role of a user.
 type Role int
 
 const (
 	RoleUnknown Role = 0
-	RoleUser    Role = 1
-	RoleAdmin   Role = 2
+	RoleUser    Role = 1 // Can read and update their inventory
+	RoleAdmin   Role = 2 // Can update every inventory
 )
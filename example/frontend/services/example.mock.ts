@@ -0,0 +1,55 @@
+import { HasPermissionRequest, HasPermissionResponse, TransferRequest, TransferResponse, WhatsTheTimeRequest, WhatsTheTimeResponse } from './example';
+
+/**
+ * ExampleMock is a mock of Example for tests which don't need a backend,
+ * provided in its place with {provide: Example, useValue: new ExampleMock()}.
+ * Each method records its payload in Calls, and resolves with the result of
+ * its Handler when set, or its Returns response, which defaults to empty.
+ */
+export class ExampleMock {
+  public HasPermissionCalls: HasPermissionRequest[] = [];
+  public HasPermissionHandler?: (payload: HasPermissionRequest) => HasPermissionResponse | Promise<HasPermissionResponse>;
+  public HasPermissionReturns?: HasPermissionResponse;
+
+  /** HasPermission reports whether the user has any of the roles. */
+  public async HasPermission(payload: HasPermissionRequest): Promise<HasPermissionResponse> {
+    this.HasPermissionCalls.push(payload);
+    if (this.HasPermissionHandler) {
+      return this.HasPermissionHandler(payload);
+    }
+    return this.HasPermissionReturns || ({} as HasPermissionResponse);
+  }
+
+  public WhatsTheTimeCalls: WhatsTheTimeRequest[] = [];
+  public WhatsTheTimeHandler?: (payload: WhatsTheTimeRequest) => WhatsTheTimeResponse | Promise<WhatsTheTimeResponse>;
+  public WhatsTheTimeReturns?: WhatsTheTimeResponse;
+
+  /**
+   * WhatsTheTime reports whether the toy was created before the date.
+   *
+   * @deprecated compare the dates in the client instead.
+   */
+  public async WhatsTheTime(payload: WhatsTheTimeRequest): Promise<WhatsTheTimeResponse> {
+    this.WhatsTheTimeCalls.push(payload);
+    if (this.WhatsTheTimeHandler) {
+      return this.WhatsTheTimeHandler(payload);
+    }
+    return this.WhatsTheTimeReturns || ({} as WhatsTheTimeResponse);
+  }
+
+  public TransferCalls: TransferRequest[] = [];
+  public TransferHandler?: (payload: TransferRequest) => TransferResponse | Promise<TransferResponse>;
+  public TransferReturns?: TransferResponse;
+
+  /**
+   * Transfer takes the cents from the balance of the user, returning the
+   * balance left and whether it is overdrawn.
+   */
+  public async Transfer(payload: TransferRequest): Promise<TransferResponse> {
+    this.TransferCalls.push(payload);
+    if (this.TransferHandler) {
+      return this.TransferHandler(payload);
+    }
+    return this.TransferReturns || ({} as TransferResponse);
+  }
+}
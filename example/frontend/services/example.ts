@@ -1,25 +1,110 @@
-import { Injectable } from '@angular/core';
-import { HttpClient } from '@angular/common/http';
+import { Inject, Injectable, InjectionToken, Optional } from '@angular/core';
+import { HttpClient, HttpHeaders } from '@angular/common/http';
 import { environment } from '../../environments/environment';
 
+// HeaderProvider returns additional headers to send with every request, such as
+// the W3C traceparent and tracestate headers of the active span.
+export type HeaderProvider = () => { [name: string]: string };
+
+export const ExampleHeaderProvider = new InjectionToken<HeaderProvider>('ExampleHeaderProvider');
+
+/** Example is served by the example backend. */
 @Injectable({
   providedIn: 'root'
 })
 export class Example {
 
-  constructor(private http: HttpClient) {}
+  constructor(private http: HttpClient, @Optional() @Inject(ExampleHeaderProvider) private headerProvider?: HeaderProvider) {}
+
+  private headers(): HttpHeaders {
+    return new HttpHeaders(this.headerProvider ? this.headerProvider() : {});
+  }
 
+  /** HasPermission reports whether the user has any of the roles. */
   // @ts-ignore
   public async HasPermission(payload: HasPermissionRequest): Promise<HasPermissionResponse> {
     // tslint:disable-next-line:max-line-length
-    return await this.http.post(environment.BackendURL + '/example/haspermission', JSON.stringify(payload)).toPromise() as HasPermissionResponse;
+    const resp = await this.http.post(environment.BackendURL + '/backend/haspermission', JSON.stringify(payload), {headers: this.headers()}).toPromise();
+    return resp as HasPermissionResponse;
   }
 
+  /**
+   * WhatsTheTime reports whether the toy was created before the date.
+   *
+   * @deprecated compare the dates in the client instead.
+   */
   // @ts-ignore
   public async WhatsTheTime(payload: WhatsTheTimeRequest): Promise<WhatsTheTimeResponse> {
     // tslint:disable-next-line:max-line-length
-    return await this.http.post(environment.BackendURL + '/example/whatsthetime', JSON.stringify(payload)).toPromise() as WhatsTheTimeResponse;
+    const resp = await this.http.post(environment.BackendURL + '/backend/whatsthetime', JSON.stringify(serializeWhatsTheTimeRequest(payload)), {headers: this.headers()}).toPromise();
+    return resp as WhatsTheTimeResponse;
+  }
+
+  /**
+   * Transfer takes the cents from the balance of the user, returning the
+   * balance left and whether it is overdrawn.
+   */
+  // @ts-ignore
+  public async Transfer(payload: TransferRequest): Promise<TransferResponse> {
+    // tslint:disable-next-line:max-line-length
+    const resp = await this.http.post(environment.BackendURL + '/backend/transfer', JSON.stringify(payload), {headers: this.headers()}).toPromise();
+    return resp as TransferResponse;
+  }
+}
+
+function mapArray(v: any, fn: (e: any) => any): any {
+  return v == null ? v : v.map(fn);
+}
+
+function mapRecord(v: any, fn: (e: any) => any): any {
+  if (v == null) {
+    return v;
   }
+
+  const r: any = {};
+  for (const k of Object.keys(v)) {
+    r[k] = fn(v[k]);
+  }
+  return r;
+}
+
+// Go encodes time.Time as an RFC3339 string.
+export function reviveDate(v: any): Date {
+  return typeof v === 'string' ? new Date(v) : v;
+}
+
+export function serializeDate(v: any): any {
+  return v instanceof Date ? v.toISOString() : v;
+}
+
+const durationUnits: { [unit: string]: number } = {
+  ns: 1e-6, us: 1e-3, µs: 1e-3, ms: 1, s: 1e3, m: 6e4, h: 3.6e6,
+};
+
+// Go encodes time.Duration as nanoseconds, or as a string such as "1h2m3s"
+// when using a custom encoding. Durations are represented as milliseconds.
+export function reviveDuration(v: any): number {
+  if (typeof v === 'number') {
+    return v / 1e6;
+  }
+
+  if (typeof v !== 'string') {
+    return v;
+  }
+
+  const sign = v.startsWith('-') ? -1 : 1;
+  let ms = 0;
+  const re = /([0-9.]+)(ns|us|µs|ms|s|m|h)/g;
+  let match = re.exec(v);
+  while (match !== null) {
+    ms += parseFloat(match[1]) * durationUnits[match[2]];
+    match = re.exec(v);
+  }
+  return sign * ms;
+}
+
+export function serializeDuration(v: any): any {
+  return typeof v === 'number' ? Math.round(v * 1e6) : v;
 }
 
 export interface HasPermissionRequest {
@@ -34,16 +119,43 @@ export interface HasPermissionResponse {
 
 export interface WhatsTheTimeRequest {
   Date: Date;
-  Toy: second.Toy;
+  Toy: Toy;
+}
+
+export function reviveWhatsTheTimeRequest(v: any): WhatsTheTimeRequest {
+  if (v == null) {
+    return v;
+  }
+
+  const r = { ...v };
+  r.Date = reviveDate(v.Date);
+  r.Toy = reviveToy(v.Toy);
+  return r;
+}
+
+export function serializeWhatsTheTimeRequest(v: WhatsTheTimeRequest): any {
+  if (v == null) {
+    return v;
+  }
+
+  const r: any = { ...v };
+  r.Date = serializeDate(v.Date);
+  r.Toy = serializeToy(v.Toy);
+  return r;
 }
 
 export interface WhatsTheTimeResponse {
   Bool: boolean;
 }
 
-export interface Toy {
-  Design: string;
-  CreatedAt: Date;
+export interface TransferRequest {
+  U: User;
+  Cents: number;
+}
+
+export interface TransferResponse {
+  Balance: number;
+  Overdrawn: boolean;
 }
 
 export interface Toy {
@@ -51,15 +163,39 @@ export interface Toy {
   CreatedAt: Date;
 }
 
+export function reviveToy(v: any): Toy {
+  if (v == null) {
+    return v;
+  }
+
+  const r = { ...v };
+  r.CreatedAt = reviveDate(v.CreatedAt);
+  return r;
+}
+
+export function serializeToy(v: Toy): any {
+  if (v == null) {
+    return v;
+  }
+
+  const r: any = { ...v };
+  r.CreatedAt = serializeDate(v.CreatedAt);
+  return r;
+}
+
+/** User is a user of the example backend. */
 export interface User {
   ID: number;
+  /** Display name */
   Name: string;
   Role: Role;
-  T: Toy;
 }
 
+/** Role is the role of a user. */
 export enum Role {
+  /** Can update every inventory */
   RoleAdmin = 2,
   RoleUnknown = 0,
+  /** Can read and update their inventory */
   RoleUser = 1,
 }
@@ -0,0 +1,444 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/luno/gobridge/config"
+	"github.com/luno/gobridge/generator"
+	"github.com/luno/gobridge/ioeasy"
+	"github.com/luno/gobridge/ir"
+	"github.com/luno/gobridge/plugin"
+	"github.com/luno/gobridge/reader"
+)
+
+func runGenerate(args []string) error {
+	fs := newFlagSet("generate", "", "generate the outputs of the APIs in the config file")
+	var a config.API
+	fs.StringVar(&a.Package, "api", "", "Directory of the API package, generates a single API instead of those in the config file")
+	mod := fs.String("mod", "", "Name of the go module being used for the project, defaults to the module in go.mod")
+	fs.StringVar(&a.TS, "ts", "", "Target location to generate the TypeScript service to")
+	fs.StringVar(&a.TSService, "ts_service", "", "Name of the TypeScript service, requires -ts")
+	fs.StringVar(&a.Server, "server", "", "Target location to generate the Go server to")
+	fs.StringVar(&a.Client, "client", "", "Target location to generate the Go client to, requires -server")
+	fs.StringVar(&a.OpenAPI, "openapi", "", "Target location to generate the OpenAPI document to")
+	fs.StringVar(&a.Contract, "contract_test", "", "Target location to generate the Go contract test of the server and client to, requires -server and -client")
+	fs.StringVar(&a.Fake, "fake", "", "Target location to generate the Go fakes of the API interfaces to")
+	fs.StringVar(&a.TSMock, "ts_mock", "", "Target location to generate the TypeScript mock service to, requires -ts")
+	fs.BoolVar(&a.Options.Int64AsString, "int64_string", false, "Encode int64 and uint64 values as JSON strings for JavaScript clients")
+	fs.BoolVar(&a.Options.Passthrough, "passthrough", false, "Send the single struct parameter and result of methods as the request and response bodies")
+	templates := fs.String("templates", "", "Directory of templates overriding the built in ones, overriding the config file")
+	var plugins pluginFlag
+	fs.Var(&plugins, "plugin", "Run the gobridge-gen-<name> plugin as `name=dir`, writing its files to dir, in addition to those in the config file; repeatable")
+	watch := fs.Bool("watch", false, "Keep running, regenerating the outputs when the API packages or config file change")
+	interval := fs.Duration("interval", 500*time.Millisecond, "How often to check for changes with -watch")
+
+	err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	var single *config.API
+	if a.Package != "" {
+		single = &a
+	}
+
+	c, err := loadConfig(single, *mod)
+	if err != nil {
+		return err
+	}
+
+	// The flags override the config file, including when -watch reloads it
+	applyFlags := func(c *config.Config) {
+		for i := range c.APIs {
+			c.APIs[i].Plugins = append(c.APIs[i].Plugins, plugins...)
+		}
+
+		if *templates != "" {
+			c.Templates = *templates
+		}
+	}
+	applyFlags(c)
+
+	if *watch {
+		return watchAPIs(c, single != nil, *interval, applyFlags)
+	}
+
+	for _, a := range c.APIs {
+		_, err := generate(c, a, "")
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// pluginFlag collects the -plugin flags.
+type pluginFlag []config.Plugin
+
+func (f *pluginFlag) String() string {
+	return ""
+}
+
+func (f *pluginFlag) Set(s string) error {
+	name, out, ok := strings.Cut(s, "=")
+	if !ok || name == "" || out == "" {
+		return errors.New("must be name=dir")
+	}
+
+	*f = append(*f, config.Plugin{Name: name, Out: out})
+	return nil
+}
+
+func runCheck(args []string) error {
+	fs := newFlagSet("check", "", "report generated files which are out of date")
+	err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	c, err := loadConfig(nil, "")
+	if err != nil {
+		return err
+	}
+
+	dir, err := os.MkdirTemp("", "gobridge")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	var stale int
+	for _, a := range c.APIs {
+		written, err := generate(c, a, dir)
+		if err != nil {
+			return err
+		}
+
+		changed, err := changedOutputs(dir, written)
+		if err != nil {
+			return err
+		}
+
+		for _, path := range changed {
+			fmt.Fprintf(os.Stderr, "%s: out of date, run gobridge generate\n", path)
+			stale++
+		}
+	}
+
+	if stale > 0 {
+		return errFailed
+	}
+
+	return nil
+}
+
+// changedOutputs returns the files generated into dir which differ from, or
+// are missing from, the working directory.
+func changedOutputs(dir string, paths []string) ([]string, error) {
+	var res []string
+	for _, path := range paths {
+		want, err := os.ReadFile(filepath.Join(dir, path))
+		if err != nil {
+			return nil, err
+		}
+
+		got, err := os.ReadFile(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+
+		if err != nil || !bytes.Equal(got, want) {
+			res = append(res, path)
+			continue
+		}
+
+		logf("%s: up to date", path)
+	}
+
+	return res, nil
+}
+
+func runOpenAPI(args []string) error {
+	fs := newFlagSet("openapi", "", "generate only the OpenAPI documents")
+	out := fs.String("o", "", "Target location of the OpenAPI document, overriding the config file when it has a single API")
+	err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	c, err := loadConfig(nil, "")
+	if err != nil {
+		return err
+	}
+
+	if *out != "" && len(c.APIs) != 1 {
+		return fmt.Errorf("-o requires a single API in the config file, found %d", len(c.APIs))
+	}
+
+	for _, a := range c.APIs {
+		if *out != "" {
+			a.OpenAPI = *out
+		}
+
+		if a.OpenAPI == "" {
+			logf("%s: no openapi output set", a.Package)
+			continue
+		}
+
+		_, err := generate(c, config.API{Package: a.Package, OpenAPI: a.OpenAPI, Options: a.Options}, "")
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadConfig returns the project config, changing the working directory to
+// that of the config file as the paths in it are relative to it. The single
+// API replaces the APIs of the config file when set, in which case the config
+// file is optional.
+func loadConfig(single *config.API, mod string) (*config.Config, error) {
+	path := configPath
+	if path == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+
+		path, err = config.Find(wd)
+		if err != nil && single == nil {
+			return nil, err
+		}
+	}
+
+	c := &config.Config{Dir: "."}
+	if path != "" {
+		var err error
+		c, err = config.Load(path)
+		if err != nil {
+			return nil, err
+		}
+		logf("using config %s", path)
+	}
+
+	if single == nil {
+		err := os.Chdir(c.Dir)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(c.APIs) == 0 {
+			return nil, fmt.Errorf("%s: no apis to generate", path)
+		}
+
+		return c, nil
+	}
+
+	if single.Client != "" && single.Server == "" {
+		return nil, errors.New("-client requires -server to be set")
+	}
+
+	if single.TS != "" && single.TSService == "" {
+		return nil, errors.New("-ts requires -ts_service to be set")
+	}
+
+	c.APIs = []config.API{*single}
+	if mod != "" {
+		c.Module = mod
+	} else {
+		var err error
+		c.Module, err = config.FindModule(".")
+		if err != nil {
+			return nil, fmt.Errorf("-mod not set: %w", err)
+		}
+	}
+
+	return c, nil
+}
+
+// generate writes the outputs of the API under dir, or in place when dir is
+// empty, and returns their paths.
+func generate(c *config.Config, a config.API, dir string) ([]string, error) {
+	d, err := parse(c, a)
+	if err != nil {
+		return nil, err
+	}
+
+	return generateFrom(c, a, d, dir)
+}
+
+// parse reads the API package.
+func parse(c *config.Config, a config.API) (*ir.API, error) {
+	d, err := reader.ParseFile(a.Package, c.Module)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(d.APIFuncs) == 0 {
+		return nil, fmt.Errorf("%s: no API interface found", a.Package)
+	}
+
+	api := d.IR()
+	printParsed(a, api)
+
+	return api, nil
+}
+
+// generateFrom writes the outputs of the API under dir, or in place when dir
+// is empty, and returns their paths.
+func generateFrom(c *config.Config, a config.API, d *ir.API, dir string) ([]string, error) {
+	opts := c.GeneratorOptions(a)
+	if dir != "" {
+		opts = append(opts, generator.WithOutputDir(dir))
+	}
+
+	var written []string
+	if a.TS != "" {
+		err := generator.TSClient(a.TS, a.TSService, d, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", a.TS, err)
+		}
+		logf("wrote %s", a.TS)
+		written = append(written, a.TS)
+	}
+
+	if a.Server != "" {
+		err := generator.Server(a.Server, d, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", a.Server, err)
+		}
+		logf("wrote %s", a.Server)
+		written = append(written, a.Server)
+	}
+
+	if a.OpenAPI != "" {
+		err := generator.OpenAPI(a.OpenAPI, d, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", a.OpenAPI, err)
+		}
+		logf("wrote %s", a.OpenAPI)
+		written = append(written, a.OpenAPI)
+	}
+
+	if a.Client != "" {
+		err := generator.GoClient(a.Client, a.Server, d, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", a.Client, err)
+		}
+		logf("wrote %s", a.Client)
+		written = append(written, a.Client)
+	}
+
+	if a.Fake != "" {
+		err := generator.Fake(a.Fake, d, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", a.Fake, err)
+		}
+		logf("wrote %s", a.Fake)
+		written = append(written, a.Fake)
+	}
+
+	if a.TSMock != "" {
+		err := generator.TSMock(a.TSMock, a.TS, a.TSService, d, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", a.TSMock, err)
+		}
+		logf("wrote %s", a.TSMock)
+		written = append(written, a.TSMock)
+	}
+
+	if a.Contract != "" {
+		err := generator.ContractTest(a.Contract, a.Server, a.Client, d, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", a.Contract, err)
+		}
+		logf("wrote %s", a.Contract)
+		written = append(written, a.Contract)
+	}
+
+	for _, p := range a.Plugins {
+		files, err := runPlugin(a, d, p, dir)
+		if err != nil {
+			return nil, fmt.Errorf("plugin %s: %w", p.Label(), err)
+		}
+		written = append(written, files...)
+	}
+
+	return written, nil
+}
+
+// runPlugin runs the plugin on the API and writes the files it returns under
+// dir, or in place when dir is empty.
+func runPlugin(a config.API, d *ir.API, p config.Plugin, dir string) ([]string, error) {
+	files, err := plugin.Run(p.Executable(), plugin.Request{
+		API:           d,
+		Options:       p.Options,
+		Int64AsString: a.Options.Int64AsString,
+		Passthrough:   a.Options.Passthrough,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var written []string
+	for _, f := range files {
+		path := filepath.Join(p.Out, filepath.FromSlash(f.Name))
+		target := filepath.Join(dir, path)
+		err := ioeasy.CreateFileFromPath(target)
+		if err != nil {
+			return nil, err
+		}
+
+		err = os.WriteFile(target, []byte(f.Content), 0o644)
+		if err != nil {
+			return nil, err
+		}
+		logf("wrote %s", path)
+		written = append(written, path)
+	}
+
+	return written, nil
+}
+
+// printParsed prints the interfaces and types read from the API package in
+// verbose mode.
+func printParsed(a config.API, api *ir.API) {
+	if !verbose {
+		return
+	}
+
+	logf("%s: package %s", a.Package, api.Package)
+
+	for _, i := range api.Interfaces {
+		logf("  interface %s", i.Name)
+		for _, m := range i.Methods {
+			logf("    %s(%s) (%s)  %s", m.Name, signature(m.Params), signature(m.Results), m.Pos)
+		}
+	}
+
+	for _, t := range api.Types {
+		switch t.Kind {
+		case ir.KindStruct:
+			logf("  struct %s.%s with %d fields", t.Package, t.Name, len(t.Fields))
+		case ir.KindEnum:
+			logf("  enum %s.%s %s with %d values", t.Package, t.Name, t.Underlying, len(t.Values))
+		}
+	}
+}
+
+func signature(fs []ir.Field) string {
+	var res []string
+	for _, f := range fs {
+		res = append(res, f.Name+" "+f.Type)
+	}
+
+	return strings.Join(res, ", ")
+}
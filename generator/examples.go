@@ -0,0 +1,135 @@
+package generator
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/luno/gobridge/ir"
+)
+
+// Example is the example response of an endpoint.
+type Example struct {
+	Method   string // Name of the API method
+	Path     string // URL path of the endpoint
+	Response json.RawMessage
+}
+
+// Examples returns an example response for each endpoint of the API,
+// synthesized from the OpenAPI schema of the response so that it's encoded
+// like the generated server would: enums take their first value, times are
+// RFC 3339 strings and slices have a single element.
+func Examples(a *ir.API, opts ...Option) ([]Example, error) {
+	o := resolveOptions(opts)
+	doc := openAPIDocument(a, o)
+	schemas := doc["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+
+	var res []Example
+	for _, api := range a.Interfaces {
+		for _, m := range api.Methods {
+			resp := m.Name + "Response"
+			if passthrough(a, m, o) {
+				resp = typeName(m.Results[0].Type)
+			}
+
+			b, err := json.Marshal(exampleValue(ref(resp), schemas, 0))
+			if err != nil {
+				return nil, err
+			}
+
+			res = append(res, Example{
+				Method:   m.Name,
+				Path:     endpointPath(a, m),
+				Response: b,
+			})
+		}
+	}
+
+	return res, nil
+}
+
+// maxExampleDepth limits the references followed by an example, so examples
+// of recursive types end.
+const maxExampleDepth = 8
+
+// exampleTime is the value of times in examples.
+var exampleTime = time.Date(2024, time.January, 2, 15, 4, 5, 0, time.UTC)
+
+// exampleValue returns a value matching the schema, looking up references in schemas.
+func exampleValue(s map[string]interface{}, schemas map[string]interface{}, depth int) interface{} {
+	if r, ok := s["$ref"].(string); ok {
+		if depth >= maxExampleDepth {
+			return nil
+		}
+
+		ref, _ := schemas[strings.TrimPrefix(r, "#/components/schemas/")].(map[string]interface{})
+		return exampleValue(ref, schemas, depth+1)
+	}
+
+	if all, ok := s["allOf"].([]interface{}); ok && len(all) > 0 {
+		first, _ := all[0].(map[string]interface{})
+		return exampleValue(first, schemas, depth)
+	}
+
+	if enum, ok := s["enum"].([]interface{}); ok && len(enum) > 0 {
+		return enum[0]
+	}
+
+	switch s["type"] {
+	case "boolean":
+		return true
+
+	case "integer":
+		return 1
+
+	case "number":
+		return 1.5
+
+	case "string":
+		switch s["format"] {
+		case "date-time":
+			return exampleTime
+		case "int64":
+			return "1"
+		case "byte":
+			return []byte("example")
+		case "uuid":
+			return "123e4567-e89b-12d3-a456-426614174000"
+		case "ip":
+			return "192.0.2.1"
+		case "decimal":
+			return "1.5"
+		}
+		return "example"
+
+	case "array":
+		items, _ := s["items"].(map[string]interface{})
+		n := 1
+		if min, ok := s["minItems"].(int); ok {
+			n = min
+		}
+
+		res := make([]interface{}, n)
+		for i := range res {
+			res[i] = exampleValue(items, schemas, depth)
+		}
+		return res
+
+	case "object":
+		res := make(map[string]interface{})
+		props, _ := s["properties"].(map[string]interface{})
+		for name, p := range props {
+			p, _ := p.(map[string]interface{})
+			res[name] = exampleValue(p, schemas, depth)
+		}
+
+		// A numeric key is valid for maps with string and integer keys alike
+		if elem, ok := s["additionalProperties"].(map[string]interface{}); ok {
+			res["1"] = exampleValue(elem, schemas, depth)
+		}
+		return res
+	}
+
+	// Schemas like {} of json.RawMessage allow any value
+	return nil
+}
@@ -1,99 +1,155 @@
 package generator
 
 import (
+	"bytes"
 	"fmt"
-	"math/rand"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"net/http"
 	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/luno/gobridge/ioeasy"
+	"github.com/luno/gobridge/ir"
 	"github.com/luno/gobridge/reader"
 	"github.com/luno/gobridge/templates"
 )
 
-func TSClient(tsPath, serviceName string, d *reader.Data) error {
-	err := ioeasy.CreateFileFromPath(tsPath)
-	if err != nil {
-		return err
-	}
+func TSClient(tsPath, serviceName string, a *ir.API, opts ...Option) error {
+	o := resolveOptions(opts)
+	o.imports = a.Imports
+	o.int64Types = int64Types(a)
+	o.names = declaredNames(a)
+	o.pkg = a.Package
+	o.tsImports = make(map[string]bool)
 
-	// Reset file
-	err = os.Truncate(tsPath, 0)
+	file, err := o.resetFile(tsPath)
 	if err != nil {
 		return err
 	}
-
-	// Apply correct permissions
-	file, err := os.OpenFile(tsPath, os.O_APPEND|os.O_WRONLY, os.ModeAppend)
-	if err != nil {
-		return err
-	}
-
-	rawTypes := d.GoTypeRep
-	fs := d.APIFuncs
-
-	var tsdata []reader.GoTypeRepresentation
-	for _, v := range rawTypes {
-		if v.Type == reader.GenericTypeStruct {
-			v.Fields = parseTSTypes(v.Fields)
-			tsdata = append(tsdata, v)
+	defer file.Close()
+
+	var tsdata []ir.Type
+	seen := make(map[string]bool)
+	for _, v := range a.Types {
+		key := v.Package + "." + v.Name
+		if seen[key] {
+			continue
 		}
+		seen[key] = true
 
-		if v.Type == reader.GenericTypeEnum {
-			v.Kind = switchToTypescriptType(v.Kind)
-			tsdata = append(tsdata, v)
+		// Mapped types are represented by their mapping rather than declared
+		if _, ok := o.types.Lookup(v.Import, v.Name); ok {
+			continue
 		}
+
+		tsdata = append(tsdata, v)
 	}
 
+	conv := newTSConverter(a.Types, o)
+
 	tsi := new(templates.TSService)
 	tsi.Name = serviceName
-	for _, methods := range fs {
-		for _, m := range methods {
-			tsi.MethodNames = append(tsi.MethodNames, m.Name)
+	if len(a.Interfaces) > 0 {
+		tsi.Doc = a.Interfaces[0].Doc
+	}
+	for _, i := range a.Interfaces {
+		for _, m := range i.Methods {
+			if passthrough(a, m, o) {
+				req, resp := typeName(m.Params[0].Type), typeName(m.Results[0].Type)
+				tsi.Methods = append(tsi.Methods, templates.TSMethod{
+					Name:             m.Name,
+					Doc:              m.Doc,
+					Service:          serviceName,
+					Path:             endpointPath(a, m),
+					Request:          req,
+					Response:         resp,
+					SerializeRequest: conv.needs[o.typeKey(req)],
+					ReviveResponse:   conv.needs[o.typeKey(resp)],
+				})
+				continue
+			}
 
+			params := paramFields(m.Params)
 			req := templates.TSInterface{
-				Name:   m.Name + "Request",
-				Fields: parseTSTypes(m.Params),
+				Name:        m.Name + "Request",
+				Fields:      parseTSTypes(params, o),
+				Conversions: conv.conversions(params, o),
 			}
 			tsi.Interfaces = append(tsi.Interfaces, req)
 
+			results := paramFields(m.Results)
 			resp := templates.TSInterface{
-				Name:   m.Name + "Response",
-				Fields: parseTSTypes(m.Results),
+				Name:        m.Name + "Response",
+				Fields:      parseTSTypes(results, o),
+				Conversions: conv.conversions(results, o),
 			}
 
 			tsi.Interfaces = append(tsi.Interfaces, resp)
+
+			tsi.Methods = append(tsi.Methods, templates.TSMethod{
+				Name:             m.Name,
+				Doc:              m.Doc,
+				Service:          serviceName,
+				Path:             endpointPath(a, m),
+				Request:          req.Name,
+				Response:         resp.Name,
+				SerializeRequest: len(req.Conversions) > 0,
+				ReviveResponse:   len(resp.Conversions) > 0,
+			})
 		}
 	}
 
 	for _, v := range tsdata {
-		switch v.Type {
-		case reader.GenericTypeStruct:
+		// The fields of the type refer to types of its package unqualified
+		to := o
+		to.pkg = v.Package
+
+		switch v.Kind {
+		case ir.KindStruct:
+			fields := encodedFields(v.Fields)
 			tst := templates.TSInterface{
-				Name:   v.Name,
-				Fields: v.Fields,
+				Name:        to.declName(v.Name),
+				Doc:         v.Doc,
+				Fields:      parseTSTypes(fields, to),
+				Conversions: conv.conversions(fields, to),
 			}
 
 			tsi.Interfaces = append(tsi.Interfaces, tst)
 
-		case reader.GenericTypeEnum:
+		case ir.KindEnum:
 			tst := templates.TSEnum{
-				Name:   v.Name,
+				Name:   to.declName(v.Name),
+				Doc:    v.Doc,
 				Fields: make(map[string]string),
+				Docs:   make(map[string]string),
 			}
 
-			l := d.ValueDecl[v.Name]
-			for _, decl := range l {
-				for key, value := range decl {
-					tst.Fields[key] = value
+			for _, val := range v.Values {
+				if o.int64AsString && to.int64Types[to.typeKey(v.Name)] {
+					tst.Fields[val.Name] = strconv.Quote(val.Value)
+				} else {
+					tst.Fields[val.Name] = val.Value
 				}
+				tst.Docs[val.Name] = val.Doc
 			}
 
 			tsi.Enums = append(tsi.Enums, tst)
 		}
 	}
 
-	err = tsi.AddTo(file)
+	for imp := range o.tsImports {
+		tsi.Imports = append(tsi.Imports, imp)
+	}
+	sort.Strings(tsi.Imports)
+
+	err = tsi.AddTo(file, o.overrides())
 	if err != nil {
 		return err
 	}
@@ -101,157 +157,559 @@ func TSClient(tsPath, serviceName string, d *reader.Data) error {
 	return nil
 }
 
-func parseTSTypes(m []reader.TypeSignature) []reader.TypeSignature {
-	temp := make([]reader.TypeSignature, len(m))
-	for i, v := range m {
-		v.Kind = switchToTypescriptType(v.Kind)
-		temp[i] = v
+func parseTSTypes(fields []ir.Field, o options) []templates.Field {
+	res := make([]templates.Field, len(fields))
+	for i, f := range fields {
+		res[i] = templates.Field{
+			Name:     tsKey(f.Name),
+			Type:     switchToTypescriptType(f.Type, o),
+			Doc:      f.Doc,
+			Optional: f.OmitEmpty(),
+		}
 	}
 
-	return temp
+	return res
 }
 
-func GoClient(data map[string]map[string]string) (fContents string, err error) {
-	return
+// encodedFields returns the fields of a struct present in its JSON encoding,
+// named by their JSON keys.
+func encodedFields(fields []ir.Field) []ir.Field {
+	var res []ir.Field
+	for _, f := range fields {
+		name, ok := f.JSONName()
+		if !ok {
+			continue
+		}
+
+		f.Name = name
+		res = append(res, f)
+	}
+
+	return res
 }
 
-func Server(serverPath, modName string, d *reader.Data) error {
-	err := ioeasy.CreateFileFromPath(serverPath)
+// paramFields returns the parameters or results of a method named by their
+// JSON keys, the fields of the generated request or response type.
+func paramFields(fields []ir.Field) []ir.Field {
+	res := make([]ir.Field, len(fields))
+	for i, f := range fields {
+		f.Name = templates.ToCamelCase(f.Name)
+		res[i] = f
+	}
+
+	return res
+}
+
+// TSMock generates a mock of the TypeScript service for frontend tests, which
+// imports the request and response types from the service at tsPath.
+func TSMock(mockPath, tsPath, serviceName string, a *ir.API, opts ...Option) error {
+	o := resolveOptions(opts)
+
+	module, err := filepath.Rel(filepath.Dir(mockPath), tsPath)
 	if err != nil {
 		return err
 	}
+	module = strings.TrimSuffix(filepath.ToSlash(module), ".ts")
+	if !strings.HasPrefix(module, ".") {
+		module = "./" + module
+	}
 
-	// Reset file
-	err = os.Truncate(serverPath, 0)
+	mock := &templates.TSMock{Name: serviceName, Module: module}
+	seen := make(map[string]bool)
+	for _, i := range a.Interfaces {
+		for _, m := range i.Methods {
+			req, resp := m.Name+"Request", m.Name+"Response"
+			if passthrough(a, m, o) {
+				req, resp = typeName(m.Params[0].Type), typeName(m.Results[0].Type)
+			}
+
+			for _, t := range []string{req, resp} {
+				if !seen[t] {
+					seen[t] = true
+					mock.Types = append(mock.Types, t)
+				}
+			}
+
+			mock.Methods = append(mock.Methods, templates.TSMethod{
+				Name:     m.Name,
+				Doc:      m.Doc,
+				Service:  serviceName,
+				Path:     endpointPath(a, m),
+				Request:  req,
+				Response: resp,
+			})
+		}
+	}
+	sort.Strings(mock.Types)
+
+	file, err := o.resetFile(mockPath)
 	if err != nil {
 		return err
 	}
+	defer file.Close()
 
-	// Apply correct permissions
-	file, err := os.OpenFile(serverPath, os.O_APPEND|os.O_WRONLY, os.ModeAppend)
+	return mock.AddTo(file, o.overrides())
+}
+
+// GoClient generates a Go HTTP client for the API. The client reuses the
+// request and response types of the generated server, importing the server
+// package when the client is generated into a different directory.
+func GoClient(clientPath, serverPath string, a *ir.API, opts ...Option) error {
+	o := resolveOptions(opts)
+	server, imports, err := httpServer(a, o)
 	if err != nil {
 		return err
+	} else if server == nil {
+		return nil
 	}
 
-	structs := d.GoTypeRep
-	apiPkgName := d.ApiPkgName
+	file, err := o.resetFile(clientPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	clientDir := filepath.Clean(filepath.Dir(clientPath))
+	serverDir := filepath.Clean(filepath.Dir(serverPath))
 
-	mrs := make(map[string]reader.GoTypeRepresentation)
-	for _, v := range structs {
-		mrs[v.Name] = v
+	cl := &templates.GoClient{
+		Package:  filepath.Base(clientDir),
+		API:      server.API,
+		Handlers: server.Handlers,
 	}
 
-	apiImport := d.ImportDictionary[apiPkgName]
-	var additionalImports []string
+	if clientDir != serverDir {
+		cl.TypesPkg = filepath.Base(serverDir)
+		cl.Imports = append(cl.Imports, reader.ImportPath(a.Module, serverDir))
+	}
 
-	fs := d.APIFuncs
-	pkgToImport := d.ImportDictionary
+	for i, h := range cl.Handlers {
+		cl.Handlers[i].Types.Request = clientArgs(h.Types.Request, cl.TypesPkg)
+	}
 
-	for api, funcs := range fs {
-		var (
-			hs []templates.HTTPHandler
-			ps []templates.Path
-		)
-		for _, fn := range funcs {
-			p := templates.Path{
-				Camelcase: fn.Name,
-				Lowercase: apiPkgName + "/" + strings.ToLower(fn.Name),
+	// Only import the packages referenced by the client method signatures
+	cl.Imports = append(cl.Imports, imports...)
+
+	return cl.AddTo(file, o.overrides())
+}
+
+// clientArgs names the parameters of a client method, renaming those which
+// would shadow the receiver, locals or types package used by the method body.
+func clientArgs(fields []templates.Field, typesPkg string) []templates.Field {
+	reserved := []string{"c", "ctx", "req", "resp", "err"}
+	if typesPkg != "" {
+		reserved = append(reserved, typesPkg)
+	}
+
+	return renameArgs(fields, reserved...)
+}
+
+// renameArgs sets the Arg of each field to its name, renaming those which are
+// reserved for other identifiers of the generated function.
+func renameArgs(fields []templates.Field, reserved ...string) []templates.Field {
+	taken := make(map[string]bool)
+	for _, name := range reserved {
+		taken[name] = true
+	}
+	for _, f := range fields {
+		taken[f.Name] = true
+	}
+
+	res := make([]templates.Field, len(fields))
+	for i, f := range fields {
+		f.Arg = f.Name
+		for _, name := range reserved {
+			if f.Name != name {
+				continue
 			}
 
-			var (
-				params         []string
-				results        []string
-				responseParams []string
-				ts             templates.SerialisationTypes
-			)
-
-			ts.Request = fn.Params
-			for i, val := range fn.Params {
-				if val.Name == "" && isBuiltInType(val.Kind) {
-					params = append(params, RandVarName())
-				} else {
-					cleaned := strings.ReplaceAll(val.Kind, "[]", "")
-					t, ok := mrs[cleaned]
-					if ok {
-						fn.Params[i].GoPackage = t.Pkg
-						if imp, exists := pkgToImport[t.Pkg]; exists {
-							if imp != apiImport {
-								additionalImports = append(additionalImports, imp)
-							}
-						}
-					} else {
-						// Core library support
-						if !isBuiltInType(val.Kind) {
-							pkg := strings.Split(val.Kind, ".")[0]
-							fn.Params[i].GoPackage = pkg
-							fn.Params[i].Kind = strings.Split(val.Kind, ".")[1]
-							if _, exists := pkgToImport[pkg]; !exists {
-								if val.Name != apiImport {
-									additionalImports = append(additionalImports, pkg)
-									pkgToImport[pkg] = pkg
-								}
-							} else if imp, exists := pkgToImport[pkg]; !exists {
-								if imp != apiImport {
-									additionalImports = append(additionalImports, imp)
-									pkgToImport[pkg] = pkg
-								}
-							}
-						}
-					}
-					params = append(params, val.Name)
+			f.Arg = f.Name + "Arg"
+			for n := 2; taken[f.Arg]; n++ {
+				f.Arg = f.Name + "Arg" + strconv.Itoa(n)
+			}
+			taken[f.Arg] = true
+		}
+		res[i] = f
+	}
+
+	return res
+}
+
+// Fake generates a configurable fake of each API interface for tests, see
+// templates.GoFake.
+func Fake(path string, a *ir.API, opts ...Option) error {
+	o := resolveOptions(opts)
+	o.imports = a.Imports
+	q := &qualifier{api: a, o: o}
+
+	f := &templates.GoFake{Package: filepath.Base(filepath.Clean(filepath.Dir(path)))}
+	for _, api := range a.Interfaces {
+		fake := templates.Fake{Name: api.Name, API: a.Package + "." + api.Name}
+		for _, m := range api.Methods {
+			fm := templates.FakeMethod{Fake: api.Name, Name: m.Name}
+			for _, p := range m.Params {
+				typ, err := q.qualify(p.Type)
+				if err != nil {
+					return fmt.Errorf("%s: %s.%s: %w", m.Pos, api.Name, m.Name, err)
 				}
+				fm.Params = append(fm.Params, templates.Field{Name: p.Name, Type: typ})
 			}
 
-			ts.Response = fn.Results
-			for i, val := range fn.Results {
-				if isBuiltInType(val.Kind) {
-					results = append(results, RandVarName())
-				} else {
-					cleaned := strings.ReplaceAll(val.Kind, "[]", "")
-					t, ok := mrs[cleaned]
-					if ok {
-						fn.Results[i].GoPackage = t.Pkg
-						if imp, exists := pkgToImport[t.Pkg]; exists {
-							if imp != apiImport {
-								additionalImports = append(additionalImports, imp)
-							}
-						}
-					}
-					results = append(results, val.Name)
+			for _, r := range m.Results {
+				typ, err := q.qualify(r.Type)
+				if err != nil {
+					return fmt.Errorf("%s: %s.%s: %w", m.Pos, api.Name, m.Name, err)
 				}
-				responseParams = append(responseParams, val.Name)
+				fm.Results = append(fm.Results, templates.Field{Name: r.Name, Type: typ})
 			}
-			responseParams = append(responseParams, "_")
-
-			results = append(results, "err")
-
-			h := templates.HTTPHandler{
-				Method:         fn.Name,
-				API:            apiPkgName + "." + api,
-				URL:            apiPkgName + "/" + strings.ToLower(fn.Name),
-				RequestType:    fn.Name,
-				Params:         params,
-				Results:        results,
-				ResponseType:   fn.Name,
-				ResponseParams: responseParams,
-				Types:          ts,
+
+			// Named to not shadow the receiver and locals of the fake's methods
+			fm.Params = renameArgs(fm.Params, "f", "ctx", "fn", "ret")
+			fake.Methods = append(fake.Methods, fm)
+		}
+		f.Fakes = append(f.Fakes, fake)
+	}
+
+	if len(f.Fakes) == 0 {
+		return nil
+	}
+	f.Imports = append(q.imports, a.Import)
+
+	file, err := o.resetFile(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return f.AddTo(file, o.overrides())
+}
+
+// ContractTest generates a Go test of the generated client and server, which
+// round trips random parameters and results of each method between them. The
+// test is in a _test package of the directory of testPath.
+func ContractTest(testPath, serverPath, clientPath string, a *ir.API, opts ...Option) error {
+	o := resolveOptions(opts)
+	server, imports, err := httpServer(a, o)
+	if err != nil {
+		return err
+	} else if server == nil {
+		return nil
+	}
+
+	file, err := o.resetFile(testPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	serverDir := filepath.Clean(filepath.Dir(serverPath))
+	clientDir := filepath.Clean(filepath.Dir(clientPath))
+
+	ct := &templates.ContractTest{
+		Package:  filepath.Base(filepath.Clean(filepath.Dir(testPath))) + "_test",
+		API:      server.API,
+		Server:   filepath.Base(serverDir),
+		Client:   filepath.Base(clientDir),
+		Handlers: server.Handlers,
+		Imports: append(imports,
+			a.Import,
+			reader.ImportPath(a.Module, serverDir),
+			reader.ImportPath(a.Module, clientDir),
+		),
+	}
+
+	return ct.AddTo(file, o.overrides())
+}
+
+func Server(serverPath string, a *ir.API, opts ...Option) error {
+	o := resolveOptions(opts)
+	server, _, err := httpServer(a, o)
+	if err != nil {
+		return err
+	} else if server == nil {
+		return nil
+	}
+
+	file, err := o.resetFile(serverPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return server.AddTo(file, o.overrides())
+}
+
+// resetFile creates the file and any missing directories, truncating any
+// previous contents.
+func (o options) resetFile(path string) (*os.File, error) {
+	path = filepath.Join(o.outDir, path)
+
+	err := ioeasy.CreateFileFromPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Reset file
+	err = os.Truncate(path, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	// Apply correct permissions
+	return os.OpenFile(path, os.O_APPEND|os.O_WRONLY, os.ModeAppend)
+}
+
+// httpServer builds the handler data shared by the server and client
+// templates for the first API interface, returning the imports needed by
+// the method signatures.
+func httpServer(a *ir.API, o options) (*templates.HTTPServer, []string, error) {
+	o.imports = a.Imports
+
+	if len(a.Interfaces) == 0 {
+		return nil, nil, nil
+	}
+
+	api := a.Interfaces[0]
+	q := &qualifier{api: a, o: o}
+
+	var (
+		hs []templates.HTTPHandler
+		ps []templates.Path
+	)
+	for _, fn := range api.Methods {
+		p := templates.Path{
+			Camelcase: fn.Name,
+			Lowercase: strings.TrimPrefix(endpointPath(a, fn), "/"),
+		}
+
+		if v, ok := fn.Annotations["timeout"]; ok {
+			timeout, err := time.ParseDuration(v)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: invalid timeout for %s.%s: %w", fn.Pos, api.Name, fn.Name, err)
 			}
+			p.Timeout = durationLiteral(timeout)
+		}
 
-			ps = append(ps, p)
-			hs = append(hs, h)
+		var err error
+		p.Deprecation, p.Sunset, err = deprecationHeaders(fn)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %s.%s: %w", fn.Pos, api.Name, fn.Name, err)
 		}
 
-		server := &templates.HTTPServer{
-			API:      apiPkgName + "." + api,
-			Imports:  append(additionalImports, apiImport),
-			Paths:    ps,
-			Handlers: hs,
+		var (
+			params         []string
+			responseParams []string
+			ts             templates.SerialisationTypes
+		)
+
+		for _, val := range fn.Params {
+			typ, err := q.qualify(val.Type)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: %s.%s: %w", fn.Pos, api.Name, fn.Name, err)
+			}
+
+			ts.Request = append(ts.Request, templates.Field{Name: val.Name, Type: typ})
+			params = append(params, val.Name)
 		}
 
-		return server.AddTo(file)
+		for _, val := range fn.Results {
+			typ, err := q.qualify(val.Type)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: %s.%s: %w", fn.Pos, api.Name, fn.Name, err)
+			}
+
+			ts.Response = append(ts.Response, templates.Field{Name: val.Name, Type: typ})
+			responseParams = append(responseParams, val.Name)
+		}
+
+		h := templates.HTTPHandler{
+			Method:         fn.Name,
+			Doc:            fn.Doc,
+			Passthrough:    passthrough(a, fn, o),
+			API:            a.Package + "." + api.Name,
+			URL:            strings.TrimPrefix(endpointPath(a, fn), "/"),
+			RequestType:    fn.Name,
+			Params:         params,
+			ResponseType:   fn.Name,
+			ResponseParams: responseParams,
+			Types:          ts,
+		}
+
+		ps = append(ps, p)
+		hs = append(hs, h)
 	}
 
-	return nil
+	return &templates.HTTPServer{
+		API:           a.Package + "." + api.Name,
+		Imports:       append(append([]string(nil), q.imports...), a.Import),
+		Paths:         ps,
+		Handlers:      hs,
+		Int64AsString: o.int64AsString,
+	}, q.imports, nil
+}
+
+// endpointPath returns the URL path the generated server serves the method on.
+func endpointPath(a *ir.API, m ir.Method) string {
+	return "/" + a.Package + "/" + strings.ToLower(m.Name)
+}
+
+// passthrough reports whether the single struct parameter and result of the
+// method are sent as the request and response bodies, see WithPassthrough.
+func passthrough(a *ir.API, m ir.Method, o options) bool {
+	if !o.passthrough || len(m.Params) != 1 || len(m.Results) != 1 {
+		return false
+	}
+
+	return isStruct(a, m.Params[0].Type) && isStruct(a, m.Results[0].Type)
+}
+
+// isStruct reports whether typ is a struct declared by the API package or a
+// package it imports.
+func isStruct(a *ir.API, typ string) bool {
+	pkg, name := a.Package, typ
+	if i := strings.LastIndex(typ, "."); i >= 0 {
+		pkg, name = typ[:i], typ[i+1:]
+	}
+
+	for _, t := range a.Types {
+		if t.Kind == ir.KindStruct && t.Package == pkg && t.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// typeName drops the package qualifier of a type.
+func typeName(typ string) string {
+	return typ[strings.LastIndex(typ, ".")+1:]
+}
+
+// deprecationHeaders returns the values of the Deprecation and Sunset response
+// headers of a method, empty when it isn't deprecated. Methods are deprecated
+// by a "Deprecated: " paragraph in their doc, which can be dated with
+// //gobridge:deprecated <date>, or by //gobridge:sunset <date>.
+func deprecationHeaders(fn ir.Method) (deprecation string, sunset string, err error) {
+	since, dated := fn.Annotations["deprecated"]
+	until, sunsets := fn.Annotations["sunset"]
+	if fn.Deprecated == "" && !dated && !sunsets {
+		return "", "", nil
+	}
+
+	// RFC 9745 dates the deprecation, while earlier drafts used true
+	deprecation = "true"
+	if since != "" {
+		t, err := time.Parse(time.DateOnly, since)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid deprecated date, expected YYYY-MM-DD: %w", err)
+		}
+		deprecation = "@" + strconv.FormatInt(t.Unix(), 10)
+	}
+
+	if until != "" {
+		t, err := time.Parse(time.DateOnly, until)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid sunset date, expected YYYY-MM-DD: %w", err)
+		}
+		sunset = t.Format(http.TimeFormat)
+	}
+
+	return deprecation, sunset, nil
+}
+
+// durationLiteral formats d as a Go expression using the largest whole unit.
+func durationLiteral(d time.Duration) string {
+	units := []struct {
+		d    time.Duration
+		name string
+	}{
+		{time.Hour, "time.Hour"},
+		{time.Minute, "time.Minute"},
+		{time.Second, "time.Second"},
+		{time.Millisecond, "time.Millisecond"},
+		{time.Microsecond, "time.Microsecond"},
+	}
+
+	for _, u := range units {
+		if d%u.d == 0 {
+			return fmt.Sprintf("%d * %s", d/u.d, u.name)
+		}
+	}
+
+	return fmt.Sprintf("%d", d)
+}
+
+// qualifier rewrites the types of the API methods for use outside of the API
+// package, collecting the imports they need.
+type qualifier struct {
+	api     *ir.API
+	o       options
+	imports []string
+	seen    map[string]bool
+}
+
+// qualify returns the Go type expression with the types of the API package
+// qualified by its name.
+func (q *qualifier) qualify(typ string) (string, error) {
+	expr, err := parser.ParseExpr(typ)
+	if err != nil {
+		return "", fmt.Errorf("invalid type %q: %w", typ, err)
+	}
+
+	expr = q.rewrite(expr)
+
+	var buf bytes.Buffer
+	err = format.Node(&buf, token.NewFileSet(), expr)
+	if err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+func (q *qualifier) rewrite(expr ast.Expr) ast.Expr {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		if isBuiltInType(e.Name) {
+			return e
+		}
+
+		q.addImport(q.api.Import)
+		return &ast.SelectorExpr{X: ast.NewIdent(q.api.Package), Sel: e}
+
+	case *ast.SelectorExpr:
+		pkg := e.X.(*ast.Ident).Name
+		if imp, ok := q.api.Imports[pkg]; ok {
+			q.addImport(imp)
+		} else if m, ok := q.o.types.lookupPackage(pkg, e.Sel.Name); ok {
+			q.addImport(m.GoImport)
+		} else {
+			// Standard library packages like time are imported by name
+			q.addImport(pkg)
+		}
+		return e
+
+	case *ast.StarExpr:
+		e.X = q.rewrite(e.X)
+	case *ast.ArrayType:
+		e.Elt = q.rewrite(e.Elt)
+	case *ast.MapType:
+		e.Key = q.rewrite(e.Key)
+		e.Value = q.rewrite(e.Value)
+	}
+
+	return expr
+}
+
+func (q *qualifier) addImport(imp string) {
+	if q.seen == nil {
+		q.seen = make(map[string]bool)
+	}
+
+	if !q.seen[imp] {
+		q.seen[imp] = true
+		q.imports = append(q.imports, imp)
+	}
 }
 
 func isBuiltInType(typ string) bool {
@@ -275,29 +733,127 @@ func isBuiltInType(typ string) bool {
 	return true
 }
 
-func switchToTypescriptType(typ string) string {
+// int64Types returns the types of the API with an int64 or uint64 underlying
+// type, which WithInt64AsString encodes as strings, keyed by typeKey.
+func int64Types(a *ir.API) map[string]bool {
+	res := make(map[string]bool)
+	for _, t := range a.Types {
+		if t.Kind == ir.KindEnum && (t.Underlying == "int64" || t.Underlying == "uint64") {
+			res[t.Package+"."+t.Name] = true
+		}
+	}
+
+	return res
+}
+
+// declaredNames returns the names of the API types in the TypeScript and
+// OpenAPI outputs, which have no packages, keyed by typeKey. Types sharing
+// their name with a type of another package are prefixed with their package,
+// like ToysToy, except for those of the API package.
+func declaredNames(a *ir.API) map[string]string {
+	pkgs := make(map[string]map[string]bool)
+	for _, t := range a.Types {
+		if pkgs[t.Name] == nil {
+			pkgs[t.Name] = make(map[string]bool)
+		}
+		pkgs[t.Name][t.Package] = true
+	}
+
+	res := make(map[string]string)
+	for _, t := range a.Types {
+		name := t.Name
+		if len(pkgs[t.Name]) > 1 && t.Package != a.Package {
+			name = strings.ToUpper(t.Package[:1]) + t.Package[1:] + t.Name
+		}
+		res[t.Package+"."+t.Name] = name
+	}
+
+	return res
+}
+
+// typeKey returns the package and name of a named type as written in the
+// package of the fields being converted, e.g. "toys.Toy" for "Toy" in toys.
+func (o options) typeKey(kind string) string {
+	if strings.Contains(kind, ".") {
+		return kind
+	}
+
+	return o.pkg + "." + kind
+}
+
+// declName returns the name of a named type in the TypeScript and OpenAPI outputs.
+func (o options) declName(kind string) string {
+	if name, ok := o.names[o.typeKey(kind)]; ok {
+		return name
+	}
+
+	return kind[strings.LastIndex(kind, ".")+1:]
+}
+
+func switchToTypescriptType(typ string, o options) string {
 	switch typ {
-	case "byte", "complex128", "complex64", "error":
+	case "int64", "uint64":
+		if o.int64AsString {
+			return "string"
+		}
+		return "number"
+	case "complex128", "complex64", "error":
 		return "string"
 	case "bool":
 		return "boolean"
 	case "float32", "float64":
 		return "number"
-	case "int", "int16", "int32", "int64", "int8":
+	case "int", "int16", "int32", "int8", "rune":
 		return "number"
-	case "rune", "string":
+	case "string":
 		return "string"
-	case "uint", "uint16", "uint32", "uint64", "uint8", "uintptr":
+	case "uint", "uint16", "uint32", "uint8", "byte", "uintptr":
 		return "number"
 	case "Time", "time.Time":
 		return "Date"
+	case "Duration", "time.Duration":
+		// Converted to milliseconds by the generated revivers
+		return "number"
+	case "[]byte", "[]uint8":
+		// Go encodes byte slices as base64 strings
+		return "string"
 	default:
 		// Consider and treat it as a non-primitive type
 
+		// Pointers are encoded as their values, or null
+		if strings.HasPrefix(typ, "*") {
+			return switchToTypescriptType(strings.TrimPrefix(typ, "*"), o)
+		}
+
+		// Fixed size arrays, including byte arrays, are encoded as JSON arrays
+		if strings.HasPrefix(typ, "[") && !strings.HasPrefix(typ, "[]") {
+			elem := typ[strings.Index(typ, "]")+1:]
+			if elem == "byte" || elem == "uint8" {
+				return "number[]"
+			}
+			return switchToTypescriptType(elem, o) + "[]"
+		}
+
+		// Named types like "type UserID int64" are encoded as their underlying type
+		if o.int64AsString && !strings.HasPrefix(typ, "[]") && !strings.HasPrefix(typ, "map") && o.int64Types[o.typeKey(typ)] {
+			return "string"
+		}
+
+		if !strings.HasPrefix(typ, "[]") && !strings.HasPrefix(typ, "map") {
+			if m, ok := o.lookupKind(typ); ok {
+				if m.TSImport != "" && o.tsImports != nil {
+					o.tsImports[m.TSImport] = true
+				}
+				return m.TS
+			}
+
+			return o.declName(typ)
+		}
+
 		// Trade Go slices for TS arrays
 		if strings.HasPrefix(typ, "[]") {
 			typ = strings.TrimPrefix(typ, "[]")
-			typ = switchToTypescriptType(typ)
+			typ = switchToTypescriptType(typ, o)
 			typ += "[]"
 		}
 
@@ -305,24 +861,11 @@ func switchToTypescriptType(typ string) string {
 		if strings.HasPrefix(typ, "map") { // map[int64]bool
 			typ = strings.TrimPrefix(typ, "map[") // int64]bool
 
-			var keyAndValueTypes []string               // 0 - key, 1 - value of a map
-			for _, v := range strings.Split(typ, "]") { // [int64, bool]
-				keyAndValueTypes = append(keyAndValueTypes, switchToTypescriptType(v))
-			}
-
-			typ = fmt.Sprintf("Record<%s, %s>", keyAndValueTypes[0], keyAndValueTypes[1])
+			// The key ends at the first bracket, as keys can't be slices or maps
+			key, value, _ := strings.Cut(typ, "]") // int64, bool
+			typ = fmt.Sprintf("Record<%s, %s>", switchToTypescriptType(key, o), switchToTypescriptType(value, o))
 		}
 
 		return typ
 	}
 }
-
-func RandVarName() string {
-	availableChars := []rune("abcdefghijklmnopqrstuvwxyz")
-
-	b := make([]rune, 4)
-	for i := range b {
-		b[i] = availableChars[rand.Intn(len(availableChars))]
-	}
-	return string(b)
-}
@@ -0,0 +1,469 @@
+package generator_test
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/luno/gobridge/generator"
+	"github.com/luno/gobridge/reader"
+)
+
+var update = flag.Bool("update", false, "Rewrite the golden files in testdata/golden with the generated outputs")
+
+// goldenCases are the API packages in testdata/golden, with the options they
+// are generated with and the directory of the module they are generated in,
+// as if the config file was there.
+var goldenCases = []struct {
+	name string
+	opts []generator.Option
+	dir  string
+}{
+	{name: "types"},
+	{name: "results"},
+	{name: "options", opts: []generator.Option{generator.WithInt64AsString(), generator.WithPassthrough()}},
+	{name: "nested", dir: "nested"},
+}
+
+// goldenOutputs are the files generated for each case, relative to the module
+// root, and the golden file each is compared with.
+var goldenOutputs = map[string]string{
+	"server/server_gen.go":      "server_gen.go.golden",
+	"client/client_gen.go":      "client_gen.go.golden",
+	"ts/api.ts":                 "api.ts.golden",
+	"openapi.json":              "openapi.json.golden",
+	"server/server_gen_test.go": "server_gen_test.go.golden",
+	"fake/fake_gen.go":          "fake_gen.go.golden",
+	"ts/api.mock.ts":            "api.mock.ts.golden",
+}
+
+// TestGolden generates the outputs of the API packages in testdata/golden and
+// compares them with the golden files, which are updated instead when run
+// with -update. The generated Go must compile and pass its contract test, and
+// the TypeScript must check.
+func TestGolden(t *testing.T) {
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go not found")
+	}
+
+	for _, c := range goldenCases {
+		t.Run(c.name, func(t *testing.T) {
+			src, err := filepath.Abs(filepath.Join("testdata", "golden", c.name))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			root := t.TempDir()
+			writeFile(t, filepath.Join(root, "go.mod"), "module example.com/golden\n\ngo 1.21\n")
+			dir := filepath.Join(root, c.dir)
+			copyDir(t, filepath.Join(src, "api"), filepath.Join(dir, "api"))
+			chdir(t, dir)
+
+			d, err := reader.ParseFile("./api", "example.com/golden")
+			if err != nil {
+				t.Fatal(err)
+			}
+			a := d.IR()
+
+			err = generator.Server("server/server_gen.go", a, c.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = generator.GoClient("client/client_gen.go", "server/server_gen.go", a, c.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = generator.TSClient("ts/api.ts", "Api", a, c.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = generator.OpenAPI("openapi.json", a, c.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = generator.Fake("fake/fake_gen.go", a, c.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = generator.TSMock("ts/api.mock.ts", "ts/api.ts", "Api", a, c.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = generator.ContractTest("server/server_gen_test.go", "server/server_gen.go", "client/client_gen.go", a, c.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for out, golden := range goldenOutputs {
+				compareGolden(t, out, filepath.Join(src, "out", golden))
+			}
+
+			cmd := exec.Command(goBin, "vet", "./...")
+			cmd.Env = append(os.Environ(), "GOWORK=off", "GOFLAGS=-mod=mod")
+			out, err := cmd.CombinedOutput()
+			if err != nil {
+				t.Fatalf("generated code doesn't compile: %v\n%s", err, out)
+			}
+
+			cmd = exec.Command(goBin, "test", "./...")
+			cmd.Env = append(os.Environ(), "GOWORK=off", "GOFLAGS=-mod=mod")
+			out, err = cmd.CombinedOutput()
+			if err != nil {
+				t.Errorf("generated contract test fails: %v\n%s", err, out)
+			}
+
+			checkTS(t, "ts/api.ts", "ts/api.mock.ts")
+		})
+	}
+}
+
+// TestTSPaths checks the TypeScript client calls the routes the server and
+// mock serve, with a service named differently to the package.
+func TestTSPaths(t *testing.T) {
+	src, err := filepath.Abs(filepath.Join("testdata", "golden", "types", "api"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "go.mod"), "module example.com/golden\n\ngo 1.21\n")
+	copyDir(t, src, filepath.Join(dir, "api"))
+	chdir(t, dir)
+
+	d, err := reader.ParseFile("./api", "example.com/golden")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := d.IR()
+
+	err = generator.TSClient("ts/shop.ts", "Shop", a)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	examples, err := generator.Examples(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var served []string
+	for _, e := range examples {
+		served = append(served, e.Path)
+	}
+	called := tsPaths(t, "ts/shop.ts")
+	sort.Strings(served)
+	sort.Strings(called)
+
+	if !reflect.DeepEqual(called, served) {
+		t.Errorf("TypeScript client calls %v, mock serves %v", called, served)
+	}
+}
+
+// compareGolden compares the generated file with the golden file, or updates
+// the golden file with -update.
+func compareGolden(t *testing.T, path, golden string) {
+	t.Helper()
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if *update {
+		writeFile(t, golden, string(got))
+		return
+	}
+
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("%v, run the tests with -update to create it", err)
+	}
+
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s differs from %s, run the tests with -update if the change is expected:\n%s",
+			path, golden, lineDiff(string(want), string(got)))
+	}
+}
+
+// lineDiff describes the first line that differs between want and got.
+func lineDiff(want, got string) string {
+	wl, gl := strings.Split(want, "\n"), strings.Split(got, "\n")
+	for i := 0; i < len(wl) || i < len(gl); i++ {
+		var w, g string
+		if i < len(wl) {
+			w = wl[i]
+		}
+		if i < len(gl) {
+			g = gl[i]
+		}
+
+		if w != g {
+			return fmt.Sprintf("line %d:\n\twant: %s\n\tgot:  %s", i+1, w, g)
+		}
+	}
+
+	return ""
+}
+
+func copyDir(t *testing.T, src, dst string) {
+	t.Helper()
+
+	err := filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		writeFile(t, filepath.Join(dst, rel), string(b))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+var (
+	tsProperty     = regexp.MustCompile(`^  ([^ /*(][^ (]*?)\??: .*;$`)
+	tsPropertyName = regexp.MustCompile(`^([A-Za-z_$][A-Za-z0-9_$]*|"[^"]*")$`)
+)
+
+// checkTS checks the generated TypeScript files with the TypeScript compiler
+// when tsc is in PATH, and parses them and checks the types and functions
+// they refer to are declared, which doesn't need it. The first file is the
+// service, which the others import from.
+func checkTS(t *testing.T, paths ...string) {
+	t.Helper()
+
+	if tsc, err := exec.LookPath("tsc"); err == nil {
+		args := append([]string{"--noEmit", "--experimentalDecorators", "--skipLibCheck", "--target", "es2017", "--lib", "es2017,dom"}, paths...)
+		out, _ := exec.Command(tsc, args...).CombinedOutput()
+		for _, l := range strings.Split(string(out), "\n") {
+			// Angular and the environment of the app aren't installed
+			if strings.Contains(l, "error TS") && !strings.Contains(l, "error TS2307") {
+				t.Errorf("tsc: %s", l)
+			}
+		}
+	}
+
+	var exported map[string]bool
+	for i, path := range paths {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		code, err := parseTS(string(b))
+		if err != nil {
+			t.Errorf("generated %s doesn't parse: %v", path, err)
+			continue
+		}
+
+		declared, imported := tsDeclarations(code)
+		if i == 0 {
+			exported = declared
+		} else {
+			for _, name := range imported {
+				if !exported[name] {
+					t.Errorf("generated %s imports %s, which %s doesn't declare", path, name, paths[0])
+				}
+			}
+		}
+
+		for _, name := range imported {
+			declared[name] = true
+		}
+		for _, name := range tsReferences(code) {
+			if !declared[name] && !tsGlobals[name] {
+				t.Errorf("generated %s refers to %s, which isn't declared", path, name)
+			}
+		}
+	}
+}
+
+var tsPost = regexp.MustCompile(`this\.http\.post\([^,]*'(/[^']*)'`)
+
+// tsPaths returns the URL paths the TypeScript client posts to.
+func tsPaths(t *testing.T, path string) []string {
+	t.Helper()
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var res []string
+	for _, m := range tsPost.FindAllStringSubmatch(string(b), -1) {
+		res = append(res, m[1])
+	}
+
+	return res
+}
+
+var (
+	tsDeclaration = regexp.MustCompile(`\b(?:interface|enum|class|type|function|const)\s+([A-Za-z_$][\w$]*)`)
+	tsImport      = regexp.MustCompile(`import\s*\{([^}]*)\}`)
+	// Capitalised identifiers in type positions, and the conversion functions
+	tsTypeRef     = regexp.MustCompile(`(?:[:<|,][ \t]*|\bas +|\bnew +)([A-Z][\w$]*)`)
+	tsFunctionRef = regexp.MustCompile(`\b((?:revive|serialize)[A-Z][\w$]*|map(?:Array|Record))\b`)
+
+	// tsGlobals are the types and values of the standard library used by the
+	// generated code.
+	tsGlobals = map[string]bool{"Array": true, "Date": true, "Error": true, "JSON": true, "Math": true, "Number": true, "Object": true, "Promise": true, "Record": true, "String": true}
+)
+
+// tsDeclarations returns the names declared by the TypeScript code, and the
+// names it imports.
+func tsDeclarations(code string) (map[string]bool, []string) {
+	declared := make(map[string]bool)
+	for _, m := range tsDeclaration.FindAllStringSubmatch(code, -1) {
+		declared[m[1]] = true
+	}
+
+	var imported []string
+	for _, m := range tsImport.FindAllStringSubmatch(code, -1) {
+		for _, name := range strings.Split(m[1], ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				imported = append(imported, name)
+			}
+		}
+	}
+
+	return declared, imported
+}
+
+// tsReferences returns the types and conversion functions the TypeScript code
+// refers to.
+func tsReferences(code string) []string {
+	var res []string
+	for _, m := range tsTypeRef.FindAllStringSubmatch(code, -1) {
+		res = append(res, m[1])
+	}
+	for _, m := range tsFunctionRef.FindAllStringSubmatch(code, -1) {
+		res = append(res, m[1])
+	}
+
+	return res
+}
+
+// parseTS checks the TypeScript tokenises with balanced brackets, that
+// interface property names are valid and that no template fields were
+// missing. It catches the broken output of template changes without needing
+// the TypeScript compiler, and returns the code with its comments and
+// literals blanked out.
+func parseTS(src string) (string, error) {
+	if strings.Contains(src, "<no value>") {
+		return "", fmt.Errorf("missing template value")
+	}
+
+	for i, l := range strings.Split(src, "\n") {
+		m := tsProperty.FindStringSubmatch(l)
+		if m != nil && !tsPropertyName.MatchString(m[1]) {
+			return "", fmt.Errorf("line %d: invalid property name %s", i+1, m[1])
+		}
+	}
+
+	closing := map[rune]rune{')': '(', ']': '[', '}': '{'}
+
+	var (
+		stack []rune
+		line  = 1
+		prev  rune // Last significant character, to tell regular expressions from division
+	)
+	runes := []rune(src)
+	code := []rune(src)
+	blank := func(from, to int) {
+		for j := from; j < to && j < len(code); j++ {
+			if code[j] != '\n' {
+				code[j] = ' '
+			}
+		}
+	}
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\n':
+			line++
+			continue
+
+		case r == ' ' || r == '\t' || r == '\r':
+			continue
+
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '/':
+			start := i
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+			blank(start, i)
+			line++
+			continue
+
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			end := strings.Index(string(runes[i+2:]), "*/")
+			if end < 0 {
+				return "", fmt.Errorf("line %d: unterminated comment", line)
+			}
+			comment := string(runes[i+2:])[:end]
+			line += strings.Count(comment, "\n")
+			blank(i, i+2+len([]rune(comment))+2)
+			i += 2 + len([]rune(comment)) + 1
+
+		case r == '\'' || r == '"' || r == '`' || (r == '/' && strings.ContainsRune("(,=:[!&|?{};", prev)):
+			start, from := line, i+1
+			for i++; i < len(runes) && runes[i] != r; i++ {
+				if runes[i] == '\\' {
+					i++
+				} else if runes[i] == '\n' {
+					if r != '`' {
+						return "", fmt.Errorf("line %d: unterminated literal", start)
+					}
+					line++
+				}
+			}
+			if i >= len(runes) {
+				return "", fmt.Errorf("line %d: unterminated literal", start)
+			}
+			blank(from, i)
+
+		case r == '(' || r == '[' || r == '{':
+			stack = append(stack, r)
+
+		case r == ')' || r == ']' || r == '}':
+			if len(stack) == 0 || stack[len(stack)-1] != closing[r] {
+				return "", fmt.Errorf("line %d: unexpected %c", line, r)
+			}
+			stack = stack[:len(stack)-1]
+		}
+
+		prev = r
+	}
+
+	if len(stack) > 0 {
+		return "", fmt.Errorf("unclosed %c", stack[len(stack)-1])
+	}
+
+	return string(code), nil
+}
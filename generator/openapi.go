@@ -0,0 +1,305 @@
+package generator
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/luno/gobridge/ir"
+)
+
+// OpenAPI generates an OpenAPI 3 document in JSON describing the endpoints of
+// the generated server.
+func OpenAPI(path string, a *ir.API, opts ...Option) error {
+	o := resolveOptions(opts)
+	b, err := json.MarshalIndent(openAPIDocument(a, o), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	file, err := o.resetFile(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(b, '\n'))
+	return err
+}
+
+// openAPIDocument returns the OpenAPI document of the API.
+func openAPIDocument(a *ir.API, o options) map[string]interface{} {
+	o.imports = a.Imports
+	o.names = declaredNames(a)
+	o.pkg = a.Package
+
+	schemas := make(map[string]interface{})
+	paths := make(map[string]interface{})
+	var tags []interface{}
+
+	for _, api := range a.Interfaces {
+		tag := map[string]interface{}{"name": api.Name}
+		describe(tag, api.Doc)
+		tags = append(tags, tag)
+
+		for _, m := range api.Methods {
+			req, resp := m.Name+"Request", m.Name+"Response"
+			if passthrough(a, m, o) {
+				req, resp = typeName(m.Params[0].Type), typeName(m.Results[0].Type)
+			} else {
+				schemas[req] = objectSchema(paramFields(m.Params), o)
+				schemas[resp] = objectSchema(paramFields(m.Results), o)
+			}
+
+			op := map[string]interface{}{
+				"operationId": m.Name,
+				"tags":        []string{api.Name},
+				"requestBody": map[string]interface{}{
+					"required": true,
+					"content":  jsonContent(req),
+				},
+				"responses": map[string]interface{}{
+					"200": map[string]interface{}{
+						"description": "Success",
+						"content":     jsonContent(resp),
+					},
+					"400": map[string]interface{}{"description": "The request body could not be decoded"},
+					"401": map[string]interface{}{"description": "Unauthorised"},
+					"413": map[string]interface{}{"description": "The request body is too large"},
+					"500": map[string]interface{}{"description": "The API returned an error"},
+					"504": map[string]interface{}{"description": "The endpoint timed out"},
+				},
+			}
+
+			if doc := strings.TrimSpace(m.Doc); doc != "" {
+				op["summary"] = summary(doc)
+				if op["summary"] != strings.Join(strings.Fields(doc), " ") {
+					op["description"] = doc
+				}
+			}
+
+			if m.Deprecated != "" || m.Annotations["sunset"] != "" {
+				op["deprecated"] = true
+			}
+
+			paths[endpointPath(a, m)] = map[string]interface{}{"post": op}
+		}
+	}
+
+	for _, t := range a.Types {
+		// The fields of the type refer to types of its package unqualified
+		to := o
+		to.pkg = t.Package
+
+		name := to.declName(t.Name)
+		if _, exists := schemas[name]; exists {
+			continue
+		}
+
+		if _, ok := o.types.Lookup(t.Import, t.Name); ok {
+			continue
+		}
+
+		var s map[string]interface{}
+		switch t.Kind {
+		case ir.KindStruct:
+			s = objectSchema(encodedFields(t.Fields), to)
+		case ir.KindEnum:
+			s = enumSchema(t, to)
+		default:
+			continue
+		}
+
+		describe(s, t.Doc)
+		if t.Deprecated != "" {
+			s["deprecated"] = true
+		}
+		schemas[name] = s
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   a.Package,
+			"version": "1.0.0",
+		},
+		"tags":  tags,
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": schemas,
+			"securitySchemes": map[string]interface{}{
+				"authorization": map[string]interface{}{
+					"type": "apiKey",
+					"in":   "header",
+					"name": "Authorization",
+				},
+			},
+		},
+		"security": []interface{}{
+			map[string]interface{}{"authorization": []string{}},
+		},
+	}
+}
+
+func jsonContent(schemaName string) map[string]interface{} {
+	return map[string]interface{}{
+		"application/json": map[string]interface{}{
+			"schema": ref(schemaName),
+		},
+	}
+}
+
+func ref(name string) map[string]interface{} {
+	return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+}
+
+func objectSchema(fields []ir.Field, o options) map[string]interface{} {
+	props := make(map[string]interface{})
+	for _, f := range fields {
+		s := openAPISchema(f.Type, o)
+		if _, isRef := s["$ref"]; isRef && strings.TrimSpace(f.Doc) != "" {
+			// Siblings of $ref are ignored, so the reference is wrapped
+			s = map[string]interface{}{"allOf": []interface{}{s}}
+		}
+
+		describe(s, f.Doc)
+		if f.Deprecated != "" {
+			s["deprecated"] = true
+		}
+		props[f.Name] = s
+	}
+
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": props,
+	}
+}
+
+func enumSchema(t ir.Type, o options) map[string]interface{} {
+	s := openAPISchema(t.Underlying, o)
+
+	var (
+		values     []interface{}
+		names      []string
+		docs       []string
+		documented bool
+	)
+	for _, v := range t.Values {
+		if unquoted, err := strconv.Unquote(v.Value); err == nil {
+			values = append(values, unquoted)
+		} else if _, err := strconv.ParseInt(v.Value, 10, 64); err == nil && s["type"] == "string" {
+			// Integers encoded as strings by WithInt64AsString
+			values = append(values, v.Value)
+		} else if n, err := strconv.ParseFloat(v.Value, 64); err == nil {
+			values = append(values, n)
+		} else {
+			continue
+		}
+
+		doc := strings.TrimSpace(v.Doc)
+		names = append(names, v.Name)
+		docs = append(docs, doc)
+		documented = documented || doc != ""
+	}
+
+	if len(values) > 0 {
+		s["enum"] = values
+	}
+
+	// Name and describe the values with the extensions understood by
+	// common code generators.
+	if documented {
+		s["x-enum-varnames"] = names
+		s["x-enum-descriptions"] = docs
+	}
+
+	return s
+}
+
+// describe sets the description of the schema or object to the doc comment.
+func describe(m map[string]interface{}, doc string) {
+	if doc = strings.TrimSpace(doc); doc != "" {
+		m["description"] = doc
+	}
+}
+
+// summary returns the first sentence of a doc comment.
+func summary(doc string) string {
+	doc = strings.Join(strings.Fields(doc), " ")
+	if i := strings.Index(doc, ". "); i >= 0 {
+		return doc[:i+1]
+	}
+
+	return doc
+}
+
+// openAPISchema returns the schema of the JSON encoding of a Go kind.
+func openAPISchema(kind string, o options) map[string]interface{} {
+	switch kind {
+	case "bool":
+		return schema("boolean", "")
+	case "string":
+		return schema("string", "")
+	case "int8", "int16", "int32", "uint8", "uint16", "byte", "rune":
+		return schema("integer", "int32")
+	case "int", "int64", "uint", "uint32", "uint64", "uintptr":
+		if o.int64AsString && (kind == "int64" || kind == "uint64") {
+			return schema("string", "int64")
+		}
+		return schema("integer", "int64")
+	case "float32":
+		return schema("number", "float")
+	case "float64":
+		return schema("number", "double")
+	case "[]byte", "[]uint8":
+		return schema("string", "byte")
+	case "Time":
+		return schema("string", "date-time")
+	}
+
+	switch {
+	case strings.HasPrefix(kind, "*"):
+		s := openAPISchema(strings.TrimPrefix(kind, "*"), o)
+		if _, isRef := s["$ref"]; isRef {
+			s = map[string]interface{}{"allOf": []interface{}{s}}
+		}
+		s["nullable"] = true
+		return s
+
+	case strings.HasPrefix(kind, "[]"):
+		return map[string]interface{}{
+			"type":  "array",
+			"items": openAPISchema(strings.TrimPrefix(kind, "[]"), o),
+		}
+
+	case strings.HasPrefix(kind, "["):
+		i := strings.Index(kind, "]")
+		s := map[string]interface{}{
+			"type":  "array",
+			"items": openAPISchema(kind[i+1:], o),
+		}
+		if n, err := strconv.Atoi(kind[1:i]); err == nil {
+			s["minItems"] = n
+			s["maxItems"] = n
+		}
+		return s
+
+	case strings.HasPrefix(kind, "map["):
+		i := strings.Index(kind, "]")
+		return map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": openAPISchema(kind[i+1:], o),
+		}
+	}
+
+	if m, ok := o.lookupKind(kind); ok {
+		s := make(map[string]interface{})
+		for k, v := range m.OpenAPI {
+			s[k] = v
+		}
+		return s
+	}
+
+	// Types from the API packages are described by their component schema
+	return ref(o.declName(kind))
+}
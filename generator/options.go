@@ -0,0 +1,78 @@
+package generator
+
+import "github.com/luno/gobridge/templates"
+
+// Option configures the generated output. The same options must be passed to
+// every generator of an API so that the server and clients agree on the wire format.
+type Option func(o *options)
+
+type options struct {
+	int64AsString bool
+	passthrough   bool
+	types         *TypeRegistry
+	imports       map[string]string // Package name to import path of the API being generated
+	int64Types    map[string]bool   // API types with an int64 or uint64 underlying type, keyed by typeKey
+	names         map[string]string // Names of the API types in the TypeScript and OpenAPI outputs, keyed by typeKey
+	pkg           string            // Package declaring the fields being converted, which unqualified types are from
+	tsImports     map[string]bool   // TypeScript import statements needed by the mapped types
+	outDir        string
+	templateDir   string
+}
+
+// WithInt64AsString encodes int64 and uint64 values as JSON strings and types
+// them as strings in TypeScript, as JavaScript numbers lose precision above 2^53.
+func WithInt64AsString() Option {
+	return func(o *options) {
+		o.int64AsString = true
+	}
+}
+
+// WithPassthrough sends the parameter and result of methods like
+// Foo(ctx, FooRequest) (FooResponse, error), which take and return a single
+// struct, as the request and response bodies as they are, instead of wrapping
+// them in generated request and response types.
+func WithPassthrough() Option {
+	return func(o *options) {
+		o.passthrough = true
+	}
+}
+
+// WithTypeMappings adds mappings for types declared outside of the API,
+// replacing any built in mapping of the same type.
+func WithTypeMappings(ms ...TypeMapping) Option {
+	return func(o *options) {
+		for _, m := range ms {
+			o.types.Register(m)
+		}
+	}
+}
+
+// WithOutputDir writes the generated files under dir instead of the working
+// directory. The paths passed to the generators still determine the package
+// names and import paths, so the output can be compared with existing files.
+func WithOutputDir(dir string) Option {
+	return func(o *options) {
+		o.outDir = dir
+	}
+}
+
+// WithTemplates replaces the built in templates with those defined in the
+// server.tmpl, client.tmpl and ts.tmpl files of dir, see templates.Overrides.
+func WithTemplates(dir string) Option {
+	return func(o *options) {
+		o.templateDir = dir
+	}
+}
+
+func (o options) overrides() templates.Overrides {
+	return templates.Overrides{Dir: o.templateDir}
+}
+
+func resolveOptions(opts []Option) options {
+	o := options{types: NewTypeRegistry()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
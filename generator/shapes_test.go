@@ -0,0 +1,135 @@
+package generator_test
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/luno/gobridge/generator"
+	"github.com/luno/gobridge/reader"
+)
+
+// shapes are API methods with the parameters and results the generated
+// handlers and clients must compile for.
+var shapes = []struct {
+	name   string
+	method string
+	opts   []generator.Option
+}{
+	{name: "only error", method: "Ping(ctx context.Context) error"},
+	{name: "no params", method: "Now(ctx context.Context) (time.Time, error)"},
+	{name: "one builtin", method: "Allowed(ctx context.Context, id int64) (bool, error)"},
+	{name: "duplicate builtins", method: "Counts(ctx context.Context, a, b int) (int, int, error)"},
+	{name: "named results", method: "Split(ctx context.Context, s string) (head, tail string, err error)"},
+	{name: "handler locals", method: "Echo(ctx context.Context, w, r string) (req, resp string, err error)"},
+	{name: "unnamed params", method: "Swap(ctx context.Context, _ string, _ string) (string, string, error)"},
+	{name: "struct", method: "Get(ctx context.Context, id int64) (User, error)"},
+	{name: "duplicate structs", method: "Pair(ctx context.Context) (User, User, error)"},
+	{name: "pointer", method: "Find(ctx context.Context, name string) (*User, error)"},
+	{name: "slice", method: "List(ctx context.Context, ids []int64) ([]User, error)"},
+	{name: "map", method: "Index(ctx context.Context) (map[string]User, error)"},
+	{name: "nested slices", method: "Transpose(ctx context.Context, grid [][]string) ([][]string, error)"},
+	{name: "slice of maps of slices", method: "Group(ctx context.Context, groups []map[string][]int) ([]map[string][]User, error)"},
+	{name: "error named type", method: "Code(ctx context.Context) (ErrorCode, error)"},
+	{name: "renamed results", method: "//gobridge:name ok why\n\tCheck(ctx context.Context) (bool, string, error)"},
+	{
+		name:   "passthrough",
+		method: "Place(ctx context.Context, req User) (User, error)",
+		opts:   []generator.Option{generator.WithPassthrough()},
+	},
+}
+
+const shapeAPI = `package api
+
+import (
+	"context"
+	"time"
+)
+
+var _ time.Time
+
+type API interface {
+	%s
+}
+
+type User struct {
+	Name string
+}
+
+type ErrorCode string
+`
+
+func TestShapesCompile(t *testing.T) {
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go not found")
+	}
+
+	for _, s := range shapes {
+		t.Run(s.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFile(t, filepath.Join(dir, "go.mod"), "module example.com/shapes\n\ngo 1.21\n")
+			writeFile(t, filepath.Join(dir, "api", "api.go"), fmt.Sprintf(shapeAPI, s.method))
+			chdir(t, dir)
+
+			d, err := reader.ParseFile("./api", "example.com/shapes")
+			if err != nil {
+				t.Fatal(err)
+			}
+			a := d.IR()
+
+			err = generator.Server("server/server_gen.go", a, s.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = generator.GoClient("client/client_gen.go", "server/server_gen.go", a, s.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			cmd := exec.Command(goBin, "vet", "./...")
+			cmd.Env = append(os.Environ(), "GOWORK=off", "GOFLAGS=-mod=mod")
+			out, err := cmd.CombinedOutput()
+			if err != nil {
+				t.Fatalf("generated code doesn't compile: %v\n%s", err, out)
+			}
+		})
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	err := os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.WriteFile(path, []byte(content), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// chdir changes the working directory for the test, as the reader resolves
+// packages relative to it.
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.Chdir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		_ = os.Chdir(wd)
+	})
+}
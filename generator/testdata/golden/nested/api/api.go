@@ -0,0 +1,15 @@
+// Package api is generated with its config in a directory of the module
+// instead of the module root.
+package api
+
+import (
+	"context"
+
+	"example.com/golden/nested/api/money"
+)
+
+// Wallet holds the money of accounts.
+type Wallet interface {
+	// Balance returns the balance of the account.
+	Balance(ctx context.Context, account string) (balance money.Amount, err error)
+}
@@ -0,0 +1,9 @@
+// Package money declares types used by the API from another package of the
+// module.
+package money
+
+// Amount is an amount of a currency.
+type Amount struct {
+	Currency string
+	Cents    int64
+}
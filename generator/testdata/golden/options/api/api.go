@@ -0,0 +1,45 @@
+// Package api covers the generator options, with int64 values encoded as
+// strings and struct parameters passed through as the request body.
+package api
+
+import (
+	"context"
+	"time"
+)
+
+type Accounts interface {
+	// Open passes the request and response through.
+	Open(ctx context.Context, req OpenRequest) (OpenResponse, error)
+
+	// Balance has builtin parameters, so isn't passed through.
+	Balance(ctx context.Context, id int64, ids []int64) (uint64, error)
+
+	// Close has several results, so isn't passed through.
+	Close(ctx context.Context, req OpenRequest) (OpenResponse, bool, error)
+
+	// Tiers has named int64 types.
+	Tiers(ctx context.Context, ids []AccountID) (map[AccountID]Tier, error)
+}
+
+type OpenRequest struct {
+	Owner string
+	Limit int64
+}
+
+type OpenResponse struct {
+	ID      int64
+	Account AccountID
+	Tier    Tier
+	Opened  time.Time
+}
+
+// AccountID identifies an account, so is encoded as a string like int64.
+type AccountID int64
+
+// Tier is the level of an account.
+type Tier uint64
+
+const (
+	TierBasic Tier = 1
+	TierGold  Tier = 2
+)
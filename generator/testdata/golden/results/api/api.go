@@ -0,0 +1,22 @@
+// Package api covers the shapes of parameters and results.
+package api
+
+import "context"
+
+type Results interface {
+	Ping(ctx context.Context) error
+	Now(ctx context.Context) (int64, error)
+	Counts(ctx context.Context, a, b int) (int, int, error)
+	Split(ctx context.Context, s string) (head, tail string, err error)
+	Echo(ctx context.Context, w, r string) (req, resp string, err error)
+	Swap(ctx context.Context, _ string, _ string) (string, string, error)
+	Pair(ctx context.Context) (User, User, error)
+	Lists(ctx context.Context) ([]User, map[string]User, error)
+
+	//gobridge:name ok why
+	Check(ctx context.Context, u User) (bool, string, error)
+}
+
+type User struct {
+	Name string
+}
@@ -0,0 +1,94 @@
+// Package api covers the types supported in parameters and results.
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"math/big"
+	"time"
+
+	"example.com/golden/api/toys"
+)
+
+// Shop sells toys.
+type Shop interface {
+	// Builtins takes each builtin type.
+	Builtins(ctx context.Context, b bool, s string, i int, i8 int8, i16 int16, i32 int32, i64 int64, u uint, u8 uint8, u16 uint16, u32 uint32, u64 uint64, f32 float32, f64 float64, r rune, bs []byte) (bool, error)
+
+	// Collections takes slices, arrays and maps.
+	Collections(ctx context.Context, ids []int64, grid [3][]string, hash [4]byte, counts map[string]int, byID map[int64]Order) ([]Order, error)
+
+	// Pointers takes pointers to builtins and structs.
+	Pointers(ctx context.Context, limit *int, order *Order) (*Order, error)
+
+	// Times takes times and durations.
+	Times(ctx context.Context, at time.Time, wait time.Duration, window []time.Time) (time.Time, error)
+
+	// Mapped takes types represented by their type mapping.
+	Mapped(ctx context.Context, total *big.Int, raw json.RawMessage, note sql.NullString) (*big.Float, error)
+
+	// Enums takes integer and string enums.
+	Enums(ctx context.Context, s Status, c toys.Colour) ([]Status, error)
+
+	// Toys takes types from a sub-package, including one named like a type of
+	// this package.
+	//
+	//gobridge:timeout 1500ms
+	Toys(ctx context.Context, t toys.Toy, box Toy) (toys.Box, error)
+
+	// Legacy is an old endpoint.
+	//
+	// Deprecated: Use Toys.
+	//
+	//gobridge:deprecated 2026-01-01
+	//gobridge:sunset 2027-01-01
+	Legacy(ctx context.Context, id int64) error
+}
+
+// Order is an order of toys.
+type Order struct {
+	ID       int64
+	Customer string `json:"customer"`
+	Note     string `json:"note,omitempty"`
+	Secret   string `json:"-"`
+	Dash     string `json:"-,"`
+	Lines    []Line
+	Extra    map[string]*Line
+	Placed   time.Time
+	Paid     *time.Time
+	Timeout  time.Duration
+	Status   Status
+
+	// Total is the sum of the lines.
+	Total float64
+
+	// Deprecated: Use Total.
+	Sum float64
+
+	internal string
+	toy      toys.Toy
+}
+
+// Line is a line of an order.
+type Line struct {
+	Toy      toys.Toy
+	Quantity int
+}
+
+// Toy is a toy with the name of a type of the toys package.
+type Toy struct {
+	Label string
+}
+
+// Status is the status of an order.
+type Status int
+
+const (
+	StatusUnknown Status = 0
+	StatusPlaced  Status = 1 // Waiting to be paid
+	StatusPaid    Status = 2
+
+	// Deprecated: Orders are no longer cancelled.
+	StatusCancelled Status = 3
+)
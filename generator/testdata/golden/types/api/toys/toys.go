@@ -0,0 +1,27 @@
+// Package toys declares types used by the API from another package.
+package toys
+
+import "time"
+
+// Toy is a toy for sale.
+type Toy struct {
+	Name    string
+	Colour  Colour
+	Created time.Time
+}
+
+// Box is a box of toys.
+type Box struct {
+	Toys []Toy
+
+	// Size of the box in centimetres.
+	Width, Height, Depth int
+}
+
+// Colour is the colour of a toy.
+type Colour string
+
+const (
+	ColourRed  Colour = "red"
+	ColourBlue Colour = "blue"
+)
@@ -0,0 +1,164 @@
+package generator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/luno/gobridge/ir"
+	"github.com/luno/gobridge/templates"
+)
+
+const (
+	directionRevive    = "revive"
+	directionSerialize = "serialize"
+)
+
+// tsConverter works out the conversions needed between the JSON encoding of
+// Go types and their TypeScript representation, such as RFC3339 strings to Date.
+type tsConverter struct {
+	structs map[string]ir.Type
+	needs   map[string]bool // Structs with fields that need converting, keyed by typeKey
+}
+
+func newTSConverter(types []ir.Type, o options) *tsConverter {
+	c := &tsConverter{
+		structs: make(map[string]ir.Type),
+		needs:   make(map[string]bool),
+	}
+
+	for _, t := range types {
+		if t.Kind == ir.KindStruct {
+			c.structs[t.Package+"."+t.Name] = t
+		}
+	}
+
+	// Keep marking structs until no more are found, as structs can
+	// reference each other in any order.
+	for changed := true; changed; {
+		changed = false
+		for key, t := range c.structs {
+			to := o
+			to.pkg = t.Package
+			if c.needs[key] || len(c.conversions(encodedFields(t.Fields), to)) == 0 {
+				continue
+			}
+
+			c.needs[key] = true
+			changed = true
+		}
+	}
+
+	return c
+}
+
+// conversions returns the conversions for the fields that need them, which
+// are declared in the package o.pkg.
+func (c *tsConverter) conversions(fields []ir.Field, o options) []templates.TSConversion {
+	var res []templates.TSConversion
+	for _, f := range fields {
+		kind := f.Type
+		prop := tsAccessor(f.Name)
+		arg := "v" + prop
+		revive := c.call(kind, arg, directionRevive, o)
+		if revive == "" {
+			continue
+		}
+
+		res = append(res, templates.TSConversion{
+			Field:     prop,
+			Revive:    revive,
+			Serialize: c.call(kind, arg, directionSerialize, o),
+		})
+	}
+
+	return res
+}
+
+// call returns the TypeScript expression converting arg of the Go kind in the
+// direction given, or an empty string when no conversion is needed.
+func (c *tsConverter) call(kind, arg, direction string, o options) string {
+	// Pointers are encoded as their values, or null which the functions pass through
+	kind = strings.TrimLeft(kind, "*")
+
+	switch {
+	case strings.HasPrefix(kind, "["):
+		i := strings.Index(kind, "]")
+		fn := c.fn(kind[i+1:], direction, o)
+		if fn == "" {
+			return ""
+		}
+		return fmt.Sprintf("mapArray(%s, %s)", arg, fn)
+
+	case strings.HasPrefix(kind, "map["):
+		i := strings.Index(kind, "]")
+		fn := c.fn(kind[i+1:], direction, o)
+		if fn == "" {
+			return ""
+		}
+		return fmt.Sprintf("mapRecord(%s, %s)", arg, fn)
+	}
+
+	fn := c.fn(kind, direction, o)
+	if fn == "" {
+		return ""
+	}
+
+	return fmt.Sprintf("%s(%s)", fn, arg)
+}
+
+// fn returns the TypeScript function converting a value of the Go kind.
+func (c *tsConverter) fn(kind, direction string, o options) string {
+	kind = strings.TrimLeft(kind, "*")
+
+	switch kind {
+	case "Time", "time.Time":
+		return direction + "Date"
+	case "Duration", "time.Duration":
+		return direction + "Duration"
+	}
+
+	if strings.HasPrefix(kind, "[") || strings.HasPrefix(kind, "map[") {
+		call := c.call(kind, "e", direction, o)
+		if call == "" {
+			return ""
+		}
+		return "(e: any) => " + call
+	}
+
+	if c.needs[o.typeKey(kind)] {
+		return direction + o.declName(kind)
+	}
+
+	return ""
+}
+
+// tsAccessor returns the property accessor of a field, quoting JSON keys which
+// aren't identifiers.
+func tsAccessor(name string) string {
+	if isTSIdentifier(name) {
+		return "." + name
+	}
+
+	return "[" + strconv.Quote(name) + "]"
+}
+
+// tsKey returns the property name of a field in an interface declaration.
+func tsKey(name string) string {
+	if isTSIdentifier(name) {
+		return name
+	}
+
+	return strconv.Quote(name)
+}
+
+func isTSIdentifier(name string) bool {
+	for i, r := range name {
+		if r != '_' && r != '$' && !unicode.IsLetter(r) && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+
+	return name != ""
+}
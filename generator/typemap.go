@@ -0,0 +1,143 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/luno/gobridge/reader"
+)
+
+// TypeMapping describes how a Go type declared outside of the API is
+// represented in the generated outputs.
+type TypeMapping struct {
+	GoImport string                 // Import path of the package declaring the type, e.g. "math/big"
+	GoType   string                 // Name of the type, e.g. "Int"
+	TS       string                 // TypeScript type of the JSON encoding
+	TSImport string                 // Optional TypeScript import statement required by TS
+	OpenAPI  map[string]interface{} // OpenAPI schema of the JSON encoding
+}
+
+func (m TypeMapping) key() string {
+	return m.GoImport + "." + m.GoType
+}
+
+// TypeRegistry holds the type mappings used by the generators, keyed by
+// import path and type name.
+type TypeRegistry struct {
+	mappings map[string]TypeMapping
+}
+
+// NewTypeRegistry returns a registry containing the built in mappings.
+func NewTypeRegistry() *TypeRegistry {
+	r := &TypeRegistry{mappings: make(map[string]TypeMapping)}
+	for _, m := range builtInMappings {
+		r.Register(m)
+	}
+
+	return r
+}
+
+// Register adds a mapping, replacing any existing mapping of the same type.
+func (r *TypeRegistry) Register(m TypeMapping) {
+	r.mappings[m.key()] = m
+}
+
+// Lookup returns the mapping for the type declared in the package with the import path.
+func (r *TypeRegistry) Lookup(importPath, name string) (TypeMapping, bool) {
+	m, ok := r.mappings[importPath+"."+name]
+	return m, ok
+}
+
+// lookupPackage returns the mapping for a type qualified by package name
+// when its import path isn't known.
+func (r *TypeRegistry) lookupPackage(pkg, name string) (TypeMapping, bool) {
+	for _, m := range r.mappings {
+		if m.GoType == name && reader.ImportName(m.GoImport) == pkg {
+			return m, true
+		}
+	}
+
+	return TypeMapping{}, false
+}
+
+// lookupKind returns the mapping of a package qualified kind like "big.Int".
+func (o options) lookupKind(kind string) (TypeMapping, bool) {
+	pkg, name, ok := strings.Cut(kind, ".")
+	if !ok || o.types == nil {
+		return TypeMapping{}, false
+	}
+
+	if imp, ok := o.imports[pkg]; ok {
+		if m, ok := o.types.Lookup(imp, name); ok {
+			return m, true
+		}
+	}
+
+	return o.types.lookupPackage(pkg, name)
+}
+
+func schema(typ, format string) map[string]interface{} {
+	s := map[string]interface{}{"type": typ}
+	if format != "" {
+		s["format"] = format
+	}
+
+	return s
+}
+
+var sqlNullTypes = map[string]struct {
+	field string
+	ts    string
+	oa    map[string]interface{}
+}{
+	"NullString":  {"String", "string", schema("string", "")},
+	"NullBool":    {"Bool", "boolean", schema("boolean", "")},
+	"NullByte":    {"Byte", "number", schema("integer", "")},
+	"NullInt16":   {"Int16", "number", schema("integer", "int32")},
+	"NullInt32":   {"Int32", "number", schema("integer", "int32")},
+	"NullInt64":   {"Int64", "number", schema("integer", "int64")},
+	"NullFloat64": {"Float64", "number", schema("number", "double")},
+	"NullTime":    {"Time", "string", schema("string", "date-time")},
+}
+
+// builtInMappings covers the standard library and well known types whose
+// JSON encoding differs from their Go structure.
+var builtInMappings = func() []TypeMapping {
+	ms := []TypeMapping{
+		{GoImport: "time", GoType: "Time", TS: "Date", OpenAPI: schema("string", "date-time")},
+		{GoImport: "time", GoType: "Duration", TS: "number", OpenAPI: schema("integer", "int64")},
+		{GoImport: "encoding/json", GoType: "RawMessage", TS: "any", OpenAPI: map[string]interface{}{}},
+		{GoImport: "encoding/json", GoType: "Number", TS: "number", OpenAPI: schema("number", "")},
+		{GoImport: "math/big", GoType: "Int", TS: "number", OpenAPI: schema("integer", "")},
+		{GoImport: "math/big", GoType: "Float", TS: "string", OpenAPI: schema("string", "")},
+		{GoImport: "math/big", GoType: "Rat", TS: "string", OpenAPI: schema("string", "")},
+		{GoImport: "net", GoType: "IP", TS: "string", OpenAPI: schema("string", "ip")},
+		{GoImport: "net/netip", GoType: "Addr", TS: "string", OpenAPI: schema("string", "ip")},
+		{GoImport: "net/url", GoType: "URL", TS: "Record<string, any>", OpenAPI: schema("object", "")},
+		{GoImport: "github.com/google/uuid", GoType: "UUID", TS: "string", OpenAPI: schema("string", "uuid")},
+		{GoImport: "github.com/gofrs/uuid", GoType: "UUID", TS: "string", OpenAPI: schema("string", "uuid")},
+		{GoImport: "github.com/satori/go.uuid", GoType: "UUID", TS: "string", OpenAPI: schema("string", "uuid")},
+		{GoImport: "github.com/shopspring/decimal", GoType: "Decimal", TS: "string", OpenAPI: schema("string", "decimal")},
+		{GoImport: "github.com/cockroachdb/apd", GoType: "Decimal", TS: "string", OpenAPI: schema("string", "decimal")},
+		{GoImport: "github.com/cockroachdb/apd/v3", GoType: "Decimal", TS: "string", OpenAPI: schema("string", "decimal")},
+	}
+
+	// The database/sql null types have no JSON encoding of their own so are
+	// encoded as their structs.
+	for name, t := range sqlNullTypes {
+		ms = append(ms, TypeMapping{
+			GoImport: "database/sql",
+			GoType:   name,
+			TS:       fmt.Sprintf("{ %s: %s; Valid: boolean }", t.field, t.ts),
+			OpenAPI: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					t.field: t.oa,
+					"Valid": schema("boolean", ""),
+				},
+			},
+		})
+	}
+
+	return ms
+}()
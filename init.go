@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/luno/gobridge/config"
+	"github.com/luno/gobridge/templates"
+)
+
+func runInit(args []string) error {
+	fs := newFlagSet("init", " <dir>", "scaffold a new API and config file")
+	name := fs.String("name", "", "Name of the API interface, defaults to the package name")
+	ts := fs.String("ts", "", "Target location to generate the TypeScript service to, none by default")
+	err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errUsage
+	}
+
+	// The API package paths must be relative to the module root
+	mod, err := config.ModulePath(".")
+	if err != nil {
+		return fmt.Errorf("gobridge init must be run in the module root: %w", err)
+	}
+
+	dir := filepath.ToSlash(filepath.Clean(fs.Arg(0)))
+	if filepath.IsAbs(dir) || strings.HasPrefix(dir, "..") || dir == "." {
+		return fmt.Errorf("%s: the API package must be a directory in the module", fs.Arg(0))
+	}
+
+	pkg := packageName(path.Base(dir))
+	if pkg == "" {
+		return fmt.Errorf("%s: no valid package name in the directory name", dir)
+	}
+
+	api := *name
+	if api == "" {
+		api = strings.ToUpper(pkg[:1]) + pkg[1:]
+	}
+
+	cfg := configPath
+	if cfg == "" {
+		cfg, err = config.Find(".")
+		if err != nil {
+			cfg = config.FileNames[0]
+		}
+	}
+
+	rel, err := filepath.Rel(dir, cfg)
+	if err != nil {
+		return err
+	}
+
+	s := &templates.Scaffold{
+		Package: pkg,
+		API:     api,
+		Import:  mod + "/" + dir,
+		Config:  filepath.ToSlash(rel),
+	}
+
+	files, err := s.Write(dir)
+	if err != nil {
+		return err
+	}
+
+	a := config.API{
+		Package: "./" + dir,
+		Server:  "./" + dir + "/server/server_gen.go",
+		Client:  "./" + dir + "/client/client_gen.go",
+		OpenAPI: "./" + dir + "/openapi.json",
+	}
+	if *ts != "" {
+		a.TS = *ts
+		a.TSService = api
+	}
+
+	err = config.AddAPI(cfg, a)
+	if err != nil {
+		return err
+	}
+	files = append(files, cfg)
+
+	c, err := config.Load(cfg)
+	if err != nil {
+		return err
+	}
+
+	written, err := generate(c, a, "")
+	if err != nil {
+		return err
+	}
+	files = append(files, written...)
+
+	sort.Strings(files)
+	for _, f := range files {
+		fmt.Println("created", filepath.Clean(f))
+	}
+
+	return nil
+}
+
+// packageName returns a valid package name from a directory name by
+// lower casing it and dropping any other characters.
+func packageName(dir string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(dir) {
+		if unicode.IsLetter(r) || (unicode.IsDigit(r) && b.Len() > 0) {
+			b.WriteRune(r)
+		}
+	}
+
+	return b.String()
+}
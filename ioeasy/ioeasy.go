@@ -4,33 +4,16 @@ import (
 	"errors"
 	"io/ioutil"
 	"os"
-	"strings"
+	"path/filepath"
 )
 
 func CreateFileFromPath(path string) error {
-	pCon := strings.Split(path, "/")
-	reconPath := "."
-	for index, item := range pCon {
-		if item == "." {
-			continue
-		}
-
-		reconPath += "/" + item
-
-		if index == (len(pCon) - 1) {
-			err := CreateFileIfNotExists(reconPath)
-			if err != nil {
-				return err
-			}
-		} else {
-			err := CreateDirIfNotExists(reconPath)
-			if err != nil {
-				return err
-			}
-		}
+	err := os.MkdirAll(filepath.Dir(path), os.ModePerm)
+	if err != nil {
+		return err
 	}
 
-	return nil
+	return CreateFileIfNotExists(path)
 }
 
 func CreateFileIfNotExists(path string) error {
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/luno/gobridge/config"
+	"github.com/luno/gobridge/ir"
+)
+
+func runIR(args []string) error {
+	fs := newFlagSet("ir", "", "print the intermediate representation of the APIs as JSON")
+	api := fs.String("api", "", "Directory of the API package, prints a single API instead of those in the config file")
+	mod := fs.String("mod", "", "Name of the go module being used for the project, defaults to the module in go.mod")
+	out := fs.String("o", "", "File to write the JSON to, defaults to stdout")
+	err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	var single *config.API
+	if *api != "" {
+		single = &config.API{Package: *api}
+	}
+
+	c, err := loadConfig(single, *mod)
+	if err != nil {
+		return err
+	}
+
+	res := make([]*ir.API, 0, len(c.APIs))
+	for _, a := range c.APIs {
+		d, err := parse(c, a)
+		if err != nil {
+			return err
+		}
+		res = append(res, d)
+	}
+
+	b, err := json.MarshalIndent(res, "", "  ")
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+
+	if *out == "" {
+		_, err = os.Stdout.Write(b)
+		return err
+	}
+
+	return os.WriteFile(*out, b, 0o644)
+}
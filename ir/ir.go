@@ -0,0 +1,152 @@
+// Package ir is the intermediate representation of an API read by gobridge,
+// consumed by the generators. It's serialised as JSON by gobridge ir and sent
+// to plugins, so changes must stay backwards compatible until Version is
+// incremented.
+package ir
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Version of the IR. It's incremented when fields are removed or change meaning.
+const Version = 1
+
+// API is an API package with the interfaces served over HTTP and the types
+// they use.
+type API struct {
+	Version int    `json:"version"`
+	Module  string `json:"module"`  // Name of the Go module, e.g. "github.com/luno/gobridge"
+	Package string `json:"package"` // Name of the API package, e.g. "backend"
+	Import  string `json:"import"`  // Import path of the API package
+
+	// Imports maps the package names used to qualify types, e.g. "second" in
+	// "second.Toy", to their import paths.
+	Imports    map[string]string `json:"imports"`
+	Interfaces []Interface       `json:"interfaces"`
+	Types      []Type            `json:"types"` // Types declared by the API package and the module packages it imports
+	Files      []string          `json:"files"` // Source files read
+}
+
+// Interface is an API interface. Each of its methods is served as an endpoint.
+type Interface struct {
+	Name    string   `json:"name"`
+	Doc     string   `json:"doc,omitempty"`
+	Methods []Method `json:"methods"`
+}
+
+// Method is an API method. The context parameter and error result every
+// method has aren't included.
+type Method struct {
+	Name        string            `json:"name"`
+	Doc         string            `json:"doc,omitempty"`
+	Deprecated  string            `json:"deprecated,omitempty"` // See Field.Deprecated
+	Params      []Field           `json:"params"`
+	Results     []Field           `json:"results"`
+	Annotations map[string]string `json:"annotations,omitempty"` // Values of //gobridge:<key> <value> comments
+	Pos         string            `json:"pos,omitempty"`         // file:line:col of the declaration
+}
+
+// Field is a struct field or a method parameter or result.
+type Field struct {
+	// Name of the field. Unnamed parameters and results are named after their type.
+	Name string `json:"name"`
+
+	// Type is the Go type expression as written in the declaring package,
+	// e.g. "[]Role" or "map[string]*second.Toy". Types from other packages
+	// are qualified by package name, resolved with API.Imports.
+	Type string `json:"type"`
+	Tag  string `json:"tag,omitempty"` // Struct tag, without the quotes
+	Doc  string `json:"doc,omitempty"`
+
+	// Deprecated is the "Deprecated: " paragraph of the doc, with the prefix,
+	// empty when not deprecated.
+	Deprecated string `json:"deprecated,omitempty"`
+}
+
+// TypeKind is the kind of a declared type.
+type TypeKind string
+
+const (
+	// KindStruct is a struct type.
+	KindStruct TypeKind = "struct"
+
+	// KindEnum is a named basic type, like "type Role int", with the constants
+	// declared of it as its values.
+	KindEnum TypeKind = "enum"
+)
+
+// Type is a named type declared in the API package or a module package it imports.
+type Type struct {
+	Name       string   `json:"name"`
+	Package    string   `json:"package"` // Name of the declaring package
+	Import     string   `json:"import"`  // Import path of the declaring package
+	Kind       TypeKind `json:"kind"`
+	Underlying string   `json:"underlying,omitempty"` // Basic type of an enum
+	Fields     []Field  `json:"fields,omitempty"`     // Exported and unexported fields of a struct
+	Values     []Value  `json:"values,omitempty"`     // Constants of an enum
+	Doc        string   `json:"doc,omitempty"`
+	Deprecated string   `json:"deprecated,omitempty"` // See Field.Deprecated
+	Pos        string   `json:"pos,omitempty"`
+}
+
+// Value is a constant of an enum.
+type Value struct {
+	Name       string `json:"name"`
+	Value      string `json:"value"` // Go literal, e.g. "1" or "\"admin\""
+	Doc        string `json:"doc,omitempty"`
+	Deprecated string `json:"deprecated,omitempty"` // See Field.Deprecated
+}
+
+// Deprecation returns the "Deprecated: " paragraph of a doc comment, which
+// marks the documented identifier as deprecated, joined into a single line.
+func Deprecation(doc string) string {
+	for _, p := range strings.Split(doc, "\n\n") {
+		p = strings.TrimSpace(p)
+		if strings.HasPrefix(p, "Deprecated: ") {
+			return strings.Join(strings.Fields(p), " ")
+		}
+	}
+
+	return ""
+}
+
+// Exported reports whether the field is exported, and so encoded in JSON.
+func (f Field) Exported() bool {
+	return f.Name != "" && strings.ToUpper(f.Name[:1]) == f.Name[:1]
+}
+
+// JSONName returns the key of the field in its JSON encoding, following the
+// json struct tag like encoding/json does. It returns false for fields which
+// aren't encoded, being unexported or tagged "-".
+func (f Field) JSONName() (string, bool) {
+	if !f.Exported() {
+		return "", false
+	}
+
+	tag := reflect.StructTag(f.Tag).Get("json")
+	if tag == "-" {
+		return "", false
+	}
+
+	// A key of "-" is given by the tag "-,"
+	name, _, _ := strings.Cut(tag, ",")
+	if name == "" {
+		return f.Name, true
+	}
+
+	return name, true
+}
+
+// OmitEmpty reports whether the field is left out of its JSON encoding when
+// it has the zero value.
+func (f Field) OmitEmpty() bool {
+	_, opts, _ := strings.Cut(reflect.StructTag(f.Tag).Get("json"), ",")
+	for _, o := range strings.Split(opts, ",") {
+		if o == "omitempty" {
+			return true
+		}
+	}
+
+	return false
+}
@@ -1,45 +1,140 @@
 package main
 
 import (
+	"errors"
 	"flag"
-	"math/rand"
-
-	"github.com/luno/gobridge/generator"
-	"github.com/luno/gobridge/reader"
+	"fmt"
+	"os"
+	"strings"
 )
 
-var (
-	inputFile     = flag.String("api", "", "Target file to read")
-	moduleName    = flag.String("mod", "", "Name of the go module being used for the project")
-	tsOutFile     = flag.String("ts", "", "Target location to generate file to read")
-	tsServiceName = flag.String("ts_service", "", "Target location to generate file to read")
-	goServerFile  = flag.String("server", "", "")
-)
+// command is a gobridge subcommand.
+type command struct {
+	Name    string
+	Summary string
+	Run     func(args []string) error
+}
+
+var commands = []command{
+	{Name: "generate", Summary: "generate the outputs of the APIs in the config file", Run: runGenerate},
+	{Name: "check", Summary: "report generated files which are out of date", Run: runCheck},
+	{Name: "openapi", Summary: "generate only the OpenAPI documents", Run: runOpenAPI},
+	{Name: "init", Summary: "scaffold a new API and config file", Run: runInit},
+	{Name: "mock", Summary: "serve example responses of an API", Run: runMock},
+	{Name: "diff", Summary: "report breaking changes to an API", Run: runDiff},
+	{Name: "ir", Summary: "print the intermediate representation of the APIs as JSON", Run: runIR},
+}
+
+// errUsage is returned by commands called with invalid arguments, after
+// reporting the problem.
+var errUsage = errors.New("usage")
+
+// errFailed is returned by commands which have already reported why they failed.
+var errFailed = errors.New("failed")
 
 func main() {
-	flag.Parse()
-	rand.Seed(123456789)
+	os.Exit(run(os.Args[1:]))
+}
 
-	if *inputFile == "" || *moduleName == "" {
-		return
+// run executes the command and returns the exit code, 2 for usage errors
+// and 1 for any other failure.
+func run(args []string) int {
+	if len(args) == 0 {
+		usage()
+		return 2
 	}
 
-	d, err := reader.ParseFile(*inputFile, *moduleName)
-	if err != nil {
-		panic(err)
+	// Support the flags only invocation of earlier versions
+	name := args[0]
+	if strings.HasPrefix(name, "-") && name != "-h" && name != "-help" && name != "--help" {
+		name, args = "generate", append([]string{"generate"}, args...)
 	}
 
-	if *tsOutFile != "" {
-		err := generator.TSClient(*tsOutFile, *tsServiceName, d)
-		if err != nil {
-			panic(err)
+	var cmd *command
+	for i := range commands {
+		if commands[i].Name == name {
+			cmd = &commands[i]
 		}
 	}
 
-	if *goServerFile != "" {
-		err = generator.Server(*goServerFile, *moduleName, d)
-		if err != nil {
-			panic(err)
+	if cmd == nil {
+		if name == "help" || name == "-h" || name == "-help" || name == "--help" {
+			usage()
+			return 0
 		}
+
+		fmt.Fprintf(os.Stderr, "gobridge: unknown command %q\n\n", name)
+		usage()
+		return 2
+	}
+
+	err := cmd.Run(args[1:])
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.Is(err, errUsage):
+		return 2
+	case errors.Is(err, errFailed):
+		return 1
+	default:
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+}
+
+func usage() {
+	fmt.Fprint(os.Stderr, `gobridge generates HTTP servers and clients from Go interfaces.
+
+Usage:
+
+	gobridge <command> [flags]
+
+Commands:
+
+`)
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "\t%-10s %s\n", c.Name, c.Summary)
+	}
+	fmt.Fprint(os.Stderr, `
+The APIs are read from gobridge.yaml in the working directory.
+Run "gobridge <command> -h" for the flags of a command.
+`)
+}
+
+// newFlagSet returns the flags of a command, including the -v and -config
+// flags shared by all of them.
+func newFlagSet(name, args, summary string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: gobridge %s [flags]%s\n\n%s.\n\nFlags:\n", name, args, strings.ToUpper(summary[:1])+summary[1:])
+		fs.PrintDefaults()
+	}
+	fs.BoolVar(&verbose, "v", false, "Print what was parsed and generated")
+	fs.StringVar(&configPath, "config", "", "Project config file (YAML or JSON), defaults to gobridge.yaml in the working directory")
+
+	return fs
+}
+
+// parseFlags parses the command's flags, returning errUsage for invalid flags.
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	err := fs.Parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return err
+	} else if err != nil {
+		return errUsage
+	}
+
+	return nil
+}
+
+var (
+	verbose    bool
+	configPath string
+)
+
+// logf prints progress in verbose mode.
+func logf(format string, args ...interface{}) {
+	if verbose {
+		fmt.Fprintf(os.Stderr, format+"\n", args...)
 	}
 }
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/luno/gobridge/config"
+	"github.com/luno/gobridge/generator"
+)
+
+func runMock(args []string) error {
+	flags := newFlagSet("mock", "", "serve example responses of an API")
+	var a config.API
+	flags.StringVar(&a.Package, "api", "", "Directory of the API package, serves a single API instead of those in the config file")
+	mod := flags.String("mod", "", "Name of the go module being used for the project, defaults to the module in go.mod")
+	flags.BoolVar(&a.Options.Int64AsString, "int64_string", false, "Encode int64 and uint64 values as JSON strings, with -api")
+	flags.BoolVar(&a.Options.Passthrough, "passthrough", false, "Respond with the single struct result of methods as the body, with -api")
+	addr := flags.String("addr", "localhost:8080", "Address to listen on")
+	fixtures := flags.String("fixtures", "", "Serve the <Method>.json files in `dir` instead of the examples of those methods")
+	err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+
+	// The fixtures are relative to the working directory, not the config file
+	if *fixtures != "" {
+		*fixtures, err = filepath.Abs(*fixtures)
+		if err != nil {
+			return err
+		}
+	}
+
+	var single *config.API
+	if a.Package != "" {
+		single = &a
+	}
+
+	c, err := loadConfig(single, *mod)
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	served := make(map[string]string)
+	for _, a := range c.APIs {
+		d, err := parse(c, a)
+		if err != nil {
+			return err
+		}
+
+		examples, err := generator.Examples(d, c.GeneratorOptions(a)...)
+		if err != nil {
+			return fmt.Errorf("%s: %w", a.Package, err)
+		}
+
+		for _, e := range examples {
+			if pkg, ok := served[e.Path]; ok {
+				return fmt.Errorf("%s is served by both %s and %s", e.Path, pkg, a.Package)
+			}
+			served[e.Path] = a.Package
+
+			mux.Handle(e.Path, mockHandler{example: e, fixtures: *fixtures})
+			logf("serving %s", e.Path)
+		}
+	}
+
+	ln, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "gobridge mock serving %d endpoints on http://%s\n", len(served), ln.Addr())
+	return http.Serve(ln, mux)
+}
+
+// mockHandler serves the example response of an endpoint, or the fixture of
+// its method when there is one.
+type mockHandler struct {
+	example  generator.Example
+	fixtures string
+}
+
+func (h mockHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, traceparent, tracestate")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, source, err := h.response()
+	if err != nil {
+		logf("%s: %v", r.URL.Path, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	logf("%s: responding with %s", r.URL.Path, source)
+
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(body)
+}
+
+// response returns the body to respond with and where it came from. Fixtures
+// are read for every request, so they can be edited while the mock runs.
+func (h mockHandler) response() ([]byte, string, error) {
+	if h.fixtures == "" {
+		return h.example.Response, "the example", nil
+	}
+
+	path := filepath.Join(h.fixtures, h.example.Method+".json")
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return h.example.Response, "the example", nil
+	} else if err != nil {
+		return nil, "", err
+	}
+
+	if !json.Valid(b) {
+		return nil, "", fmt.Errorf("fixture %s isn't valid JSON", path)
+	}
+
+	return b, path, nil
+}
@@ -0,0 +1,144 @@
+// Package plugin runs generators shipped separately from gobridge, similar to
+// protoc plugins. A plugin is an executable named gobridge-gen-<name> which
+// reads a Request as JSON on stdin and writes a Response as JSON on stdout.
+// Plugins written in Go can use Main to handle the protocol.
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/luno/gobridge/ir"
+)
+
+// Prefix is the prefix of plugin executable names.
+const Prefix = "gobridge-gen-"
+
+// Request is sent to a plugin on stdin.
+type Request struct {
+	API *ir.API `json:"api"`
+
+	// Options are the plugin's options from the config file.
+	Options map[string]string `json:"options,omitempty"`
+
+	// Int64AsString is set when int64 and uint64 values are encoded as JSON
+	// strings, which the plugin's output must agree with.
+	Int64AsString bool `json:"int64_string,omitempty"`
+
+	// Passthrough is set when the single struct parameter and result of
+	// methods are the request and response bodies, see generator.WithPassthrough.
+	Passthrough bool `json:"passthrough,omitempty"`
+}
+
+// Response is written by a plugin to stdout.
+type Response struct {
+	Files []File `json:"files"`
+
+	// Error reports a problem with the API, such as an unsupported type. The
+	// plugin should exit with status 0 when it's set, as non-zero statuses are
+	// for failures of the plugin itself.
+	Error string `json:"error,omitempty"`
+}
+
+// File is a file generated by a plugin.
+type File struct {
+	// Name of the file, a slash separated path relative to the plugin's output directory.
+	Name    string `json:"name"`
+	Content string `json:"content"`
+}
+
+// Executable returns the name of the executable of the plugin.
+func Executable(name string) string {
+	return Prefix + name
+}
+
+// Run runs the plugin executable, looked up in PATH when it doesn't contain a
+// path separator, and returns the files it generated. What the plugin writes
+// to stderr is passed through.
+func Run(exe string, req Request) ([]File, error) {
+	b, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var stdout bytes.Buffer
+	cmd := exec.Command(exe)
+	cmd.Stdin = bytes.NewReader(b)
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+
+	err = cmd.Run()
+	if errors.Is(err, exec.ErrNotFound) {
+		return nil, fmt.Errorf("%s not found in PATH", exe)
+	} else if err != nil {
+		return nil, fmt.Errorf("running %s: %w", exe, err)
+	}
+
+	var resp Response
+	err = json.Unmarshal(stdout.Bytes(), &resp)
+	if err != nil {
+		return nil, fmt.Errorf("invalid response: %w", err)
+	}
+
+	if resp.Error != "" {
+		return nil, errors.New(resp.Error)
+	}
+
+	for _, f := range resp.Files {
+		err := checkName(f.Name)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return resp.Files, nil
+}
+
+// checkName makes sure a file stays within the output directory.
+func checkName(name string) error {
+	clean := path.Clean(name)
+	if name == "" || path.IsAbs(clean) || filepath.IsAbs(name) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return fmt.Errorf("invalid file name %q, must be relative to the output directory", name)
+	}
+
+	return nil
+}
+
+// Main implements the plugin side of the protocol, calling generate with the
+// request read from stdin and writing the files it returns to stdout. Errors
+// returned by generate are reported in the response.
+func Main(generate func(req *Request) ([]File, error)) {
+	err := handle(os.Stdin, os.Stdout, generate)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func handle(r io.Reader, w io.Writer, generate func(req *Request) ([]File, error)) error {
+	var req Request
+	err := json.NewDecoder(r).Decode(&req)
+	if err != nil {
+		return fmt.Errorf("reading request: %w", err)
+	}
+
+	if req.API == nil || req.API.Version != ir.Version {
+		return fmt.Errorf("unsupported request, expected IR version %d", ir.Version)
+	}
+
+	var resp Response
+	resp.Files, err = generate(&req)
+	if err != nil {
+		resp = Response{Error: err.Error()}
+	}
+
+	return json.NewEncoder(w).Encode(resp)
+}
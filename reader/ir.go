@@ -0,0 +1,100 @@
+package reader
+
+import (
+	"sort"
+
+	"github.com/luno/gobridge/ir"
+)
+
+// IR returns the intermediate representation of the API read.
+func (d *Data) IR() *ir.API {
+	a := &ir.API{
+		Version: ir.Version,
+		Module:  d.Module,
+		Package: d.ApiPkgName,
+		Import:  d.ImportDictionary[d.ApiPkgName],
+		Imports: d.ImportDictionary,
+		Files:   d.Files,
+	}
+
+	var names []string
+	for name := range d.APIFuncs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		i := ir.Interface{Name: name, Doc: d.APIDocs[name]}
+		for _, m := range d.APIFuncs[name] {
+			i.Methods = append(i.Methods, ir.Method{
+				Name:        m.Name,
+				Doc:         m.Doc,
+				Deprecated:  ir.Deprecation(m.Doc),
+				Params:      irFields(m.Params),
+				Results:     irFields(m.Results),
+				Annotations: m.Annotations,
+				Pos:         m.Pos.String(),
+			})
+		}
+		a.Interfaces = append(a.Interfaces, i)
+	}
+
+	// Packages imported by multiple files are read more than once
+	seen := make(map[string]bool)
+	for _, t := range d.GoTypeRep {
+		key := t.ImportPath + "." + t.Name
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		typ := ir.Type{
+			Name:       t.Name,
+			Package:    t.Pkg,
+			Import:     t.ImportPath,
+			Doc:        t.Doc,
+			Deprecated: ir.Deprecation(t.Doc),
+			Pos:        t.Pos.String(),
+		}
+
+		switch t.Type {
+		case GenericTypeStruct:
+			typ.Kind = ir.KindStruct
+			typ.Fields = irFields(t.Fields)
+		case GenericTypeEnum:
+			typ.Kind = ir.KindEnum
+			typ.Underlying = t.Kind
+			for _, decl := range d.ValueDecl[t.Name] {
+				for name, value := range decl {
+					typ.Values = append(typ.Values, ir.Value{
+						Name:       name,
+						Value:      value,
+						Doc:        d.ValueDocs[name],
+						Deprecated: ir.Deprecation(d.ValueDocs[name]),
+					})
+				}
+			}
+		default:
+			continue
+		}
+
+		a.Types = append(a.Types, typ)
+	}
+
+	return a
+}
+
+func irFields(ts []TypeSignature) []ir.Field {
+	res := make([]ir.Field, 0, len(ts))
+	for _, t := range ts {
+		res = append(res, ir.Field{
+			Name:       t.Name,
+			Type:       t.Kind,
+			Tag:        t.Tag,
+			Doc:        t.Doc,
+			Deprecated: ir.Deprecation(t.Doc),
+		})
+	}
+
+	return res
+}
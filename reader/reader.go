@@ -1,20 +1,29 @@
 package reader
 
 import (
+	"errors"
 	"fmt"
 	"go/ast"
 	"go/parser"
 	"go/token"
+	"io/fs"
 	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 )
 
 type Data struct {
 	GoTypeRep        []GoTypeRepresentation
 	APIFuncs         map[string][]FunctionSignature
+	APIDocs          map[string]string // Doc comments of the API interfaces
 	ApiPkgName       string
-	ImportDictionary map[string]string              // Package name to import path from go mod
+	Module           string
+	ImportDictionary map[string]string              // Package name to import path, from go mod and the imports of the files read
 	ValueDecl        map[string][]map[string]string // Constants and var declarations found
+	ValueDocs        map[string]string              // Doc comments of the constants, by name
+	Files            []string                       // Paths of the source files read, including those of imported module packages
 }
 
 func ParseFile(path string, mName string) (*Data, error) {
@@ -29,7 +38,7 @@ func ParseFile(path string, mName string) (*Data, error) {
 
 	var filesToRead []string
 	for _, v := range dirInfo {
-		if v.IsDir() {
+		if !isSourceFile(v) {
 			continue
 		}
 
@@ -38,9 +47,12 @@ func ParseFile(path string, mName string) (*Data, error) {
 
 	fset := token.NewFileSet()
 	d := &Data{
+		Module:           mName,
 		APIFuncs:         make(map[string][]FunctionSignature),
+		APIDocs:          make(map[string]string),
 		ImportDictionary: make(map[string]string),
 		ValueDecl:        make(map[string][]map[string]string),
+		ValueDocs:        make(map[string]string),
 	}
 
 	for _, filePath := range filesToRead {
@@ -59,7 +71,9 @@ func readFile(fset *token.FileSet, mName string, filePath string, d *Data, recur
 		return err
 	}
 
-	node, err := parser.ParseFile(fset, "", string(b), parser.ParseComments)
+	d.Files = append(d.Files, filePath)
+
+	node, err := parser.ParseFile(fset, filePath, b, parser.ParseComments)
 	if err != nil {
 		return err
 	}
@@ -68,41 +82,87 @@ func readFile(fset *token.FileSet, mName string, filePath string, d *Data, recur
 		fset: fset,
 	}
 
+	// Errors can't be returned from ast.Inspect so are collected instead
+	var errs []error
+
+	// Type declarations are documented on the type keyword unless grouped
+	var declDoc *ast.CommentGroup
+
 	var sp []TypeSignature
 	// Traverse file tree for interface methods
 	ast.Inspect(node, func(n ast.Node) bool {
 		switch t := n.(type) {
+		case *ast.GenDecl:
+			declDoc = nil
+			if !t.Lparen.IsValid() {
+				declDoc = t.Doc
+			}
 		case *ast.File:
 			r.CurrGoPkg = t.Name.Name
-			d.ImportDictionary[r.CurrGoPkg] = mName + getDirFromPath(filePath)
+			d.ImportDictionary[r.CurrGoPkg] = ImportPath(mName, filepath.Dir(filePath))
+
+			// Record the other imports so qualified types like big.Int can be
+			// resolved to their import path.
+			for _, imp := range t.Imports {
+				path := strings.Trim(imp.Path.Value, `"`)
+				name := ImportName(path)
+				if imp.Name != nil {
+					name = imp.Name.Name
+				}
+
+				if name == "_" || name == "." {
+					continue
+				}
+
+				if _, exists := d.ImportDictionary[name]; !exists {
+					d.ImportDictionary[name] = path
+				}
+			}
 		case *ast.TypeSpec:
+			doc := t.Doc
+			if doc == nil {
+				doc = declDoc
+			}
+
 			if t.Name.IsExported() {
 				switch assertion := t.Type.(type) {
 				case *ast.StructType:
 					sp = r.ListStructProperties(assertion)
 					d.GoTypeRep = append(d.GoTypeRep, GoTypeRepresentation{
-						Name:   t.Name.Name,
-						Pkg:    r.CurrGoPkg,
-						Type:   GenericTypeStruct,
-						Fields: sp,
+						Name:       t.Name.Name,
+						Pkg:        r.CurrGoPkg,
+						ImportPath: d.ImportDictionary[r.CurrGoPkg],
+						Type:       GenericTypeStruct,
+						Fields:     sp,
+						Doc:        doc.Text(),
+						Pos:        r.fset.Position(t.Pos()),
 					})
 				case *ast.InterfaceType:
 					if recursive == true {
 						apiFs := r.ListInterfaceMethods(assertion)
+						errs = append(errs, r.checkMethods(assertion)...)
 						d.APIFuncs[t.Name.Name] = apiFs
+						d.APIDocs[t.Name.Name] = doc.Text()
 						d.ApiPkgName = r.CurrGoPkg
 					}
 				case *ast.Ident:
 					// Possible enum of a primitive type
 					d.GoTypeRep = append(d.GoTypeRep, GoTypeRepresentation{
-						Name: t.Name.Name,
-						Pkg:  r.CurrGoPkg,
-						Type: GenericTypeEnum,
-						Kind: assertion.Name,
+						Name:       t.Name.Name,
+						Pkg:        r.CurrGoPkg,
+						ImportPath: d.ImportDictionary[r.CurrGoPkg],
+						Type:       GenericTypeEnum,
+						Kind:       assertion.Name,
+						Doc:        doc.Text(),
+						Pos:        r.fset.Position(t.Pos()),
 					})
 				}
 			}
 		case *ast.ValueSpec:
+			if len(t.Values) == 0 {
+				return true
+			}
+
 			_, ok := t.Values[0].(*ast.BasicLit)
 			if ok {
 				m := make(map[string]string)
@@ -111,10 +171,22 @@ func readFile(fset *token.FileSet, mName string, filePath string, d *Data, recur
 				_, isIdent := t.Type.(*ast.Ident)
 				if isIdent {
 					d.ValueDecl[t.Type.(*ast.Ident).Name] = append(d.ValueDecl[t.Type.(*ast.Ident).Name], m)
+
+					doc := t.Doc
+					if doc == nil {
+						doc = t.Comment
+					}
+					if doc == nil {
+						doc = declDoc
+					}
+					if text := doc.Text(); text != "" {
+						d.ValueDocs[t.Names[0].Name] = text
+					}
 				}
 			}
 		case *ast.ImportSpec:
-			if !strings.Contains(t.Path.Value, mName) {
+			path := strings.Trim(t.Path.Value, `"`)
+			if !strings.HasPrefix(path, mName+"/") {
 				return false
 			}
 
@@ -122,39 +194,41 @@ func readFile(fset *token.FileSet, mName string, filePath string, d *Data, recur
 				return false
 			}
 
-			dirLvlCorrection := ""
-			path := strings.ReplaceAll(t.Path.Value, `"`, "")
-			if strings.HasPrefix(path, mName) {
-				path = strings.ReplaceAll(path, mName, "")
-			} else {
+			dir, err := packageDir(filepath.Dir(filePath), strings.TrimPrefix(path, mName+"/"))
+			if err != nil {
+				errs = append(errs, r.errorf(t.Pos(), "reading imported package: %v", err))
 				return false
 			}
 
-			pathList := strings.Split(path, "/")
-			path = strings.Join(pathList[1:], "/")
-			dirLvlCorrection = strings.Split(filePath, strings.Split(path, "/")[0])[0]
-
-			fi, err := ioutil.ReadDir(dirLvlCorrection + path)
+			fi, err := ioutil.ReadDir(dir)
 			if err != nil {
-				panic(err)
+				errs = append(errs, r.errorf(t.Pos(), "reading imported package: %v", err))
+				return false
 			}
 
 			for _, f := range fi {
-				if f.IsDir() {
+				if !isSourceFile(f) {
 					continue
 				}
 
-				nextPath := dirLvlCorrection + path + "/" + f.Name()
+				nextPath := filepath.Join(dir, f.Name())
 				err = readFile(fset, mName, nextPath, d, false)
 				if err != nil {
-					panic(err)
+					errs = append(errs, err)
 				}
 			}
 		}
 		return true
 	})
 
-	return nil
+	errs = append(errs, r.errs...)
+	return errors.Join(errs...)
+}
+
+// isSourceFile reports whether the file is part of the package's API, which
+// excludes tests and any non Go files in the directory.
+func isSourceFile(fi fs.FileInfo) bool {
+	return !fi.IsDir() && strings.HasSuffix(fi.Name(), ".go") && !strings.HasSuffix(fi.Name(), "_test.go")
 }
 
 func getDirFromPath(path string) string {
@@ -177,37 +251,117 @@ func getDirFromPath(path string) string {
 	return dir
 }
 
+// ModuleRoot returns the directory of the go.mod file of the module which dir
+// is in, the nearest one in dir or its parents.
+func ModuleRoot(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	for d := abs; ; d = filepath.Dir(d) {
+		if _, err := os.Stat(filepath.Join(d, "go.mod")); err == nil {
+			return d, nil
+		}
+
+		if filepath.Dir(d) == d {
+			return "", fmt.Errorf("no go.mod in %s or its parents", abs)
+		}
+	}
+}
+
+// ImportPath returns the import path of the package in dir, a directory of
+// the module mName. Without a go.mod file dir is taken to be relative to the
+// module root.
+func ImportPath(mName, dir string) string {
+	root, err := ModuleRoot(dir)
+	if err != nil {
+		return mName + getDirFromPath(filepath.ToSlash(dir))
+	}
+
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return mName + getDirFromPath(filepath.ToSlash(dir))
+	}
+
+	rel, err := filepath.Rel(root, abs)
+	if err != nil || rel == "." {
+		return mName
+	}
+
+	return mName + "/" + filepath.ToSlash(rel)
+}
+
+// packageDir returns the directory of the package at the path relative to the
+// root of the module which dir is in, relative to the working directory when
+// it can be.
+func packageDir(dir, rel string) (string, error) {
+	root, err := ModuleRoot(dir)
+	if err != nil {
+		return "", err
+	}
+
+	res := filepath.Join(root, filepath.FromSlash(rel))
+	if wd, err := os.Getwd(); err == nil {
+		if r, err := filepath.Rel(wd, res); err == nil {
+			return r, nil
+		}
+	}
+
+	return res, nil
+}
+
 type Reader struct {
 	fset      *token.FileSet
 	CurrGoPkg string
+	errs      []error
+}
+
+// errorf returns a diagnostic prefixed with the file:line:col of the source
+// position, like the compiler's.
+func (r *Reader) errorf(pos token.Pos, format string, args ...interface{}) error {
+	return fmt.Errorf("%s: %s", r.fset.Position(pos), fmt.Sprintf(format, args...))
 }
 
 // ListInterfaceMethods returns the function signatures of all the interface methods
 func (r *Reader) ListStructProperties(it *ast.StructType) []TypeSignature {
 	var sf []TypeSignature
 	for _, field := range it.Fields.List {
+		if len(field.Names) == 0 {
+			r.errs = append(r.errs, r.errorf(field.Pos(), "embedded fields are not supported"))
+			continue
+		}
+
 		// Name
 		ts := TypeSignature{}
 		ts.Name = field.Names[0].Name
+		if field.Tag != nil {
+			ts.Tag, _ = strconv.Unquote(field.Tag.Value)
+		}
+
+		ts.Doc = field.Doc.Text()
+		if ts.Doc == "" {
+			ts.Doc = field.Comment.Text()
+		}
 		// Kind
 		switch t := field.Type.(type) {
 		case *ast.Ident:
 			ts.Kind = t.Name
-		case *ast.SelectorExpr:
-			p2 := t.Sel
-			ts.Kind = p2.Name
-		case *ast.ArrayType:
-			ts.Kind = "[]" + r.importTypeFromASTExpr(t.Elt)
+		case *ast.SelectorExpr, *ast.ArrayType, *ast.StarExpr:
+			ts.Kind = r.importTypeFromASTExpr(t)
 		case *ast.MapType:
 			p1 := r.importTypeFromASTExpr(t.Key)
 			p2 := r.importTypeFromASTExpr(t.Value)
 			ts.Kind = fmt.Sprintf("map[%s]%s", p1, p2)
 		default:
-			fmt.Println("Unable to determine type:")
-			ast.Print(r.fset, t)
+			r.errs = append(r.errs, r.errorf(field.Pos(), "unsupported type of field %s", ts.Name))
 		}
 
-		sf = append(sf, ts)
+		// Fields like "A, B int" share their type, tag and doc
+		for _, n := range field.Names {
+			ts.Name = n.Name
+			sf = append(sf, ts)
+		}
 	}
 	return sf
 }
@@ -227,6 +381,8 @@ type GoTypeRepresentation struct {
 	Fields     []TypeSignature // This will only have a value if the GenericType is set to Struct
 	Type       GenericType
 	Kind       string // This will only have a value if the GenericType is set to Enum
+	Doc        string
+	Pos        token.Position
 }
 
 type TypeSignature struct {
@@ -234,6 +390,8 @@ type TypeSignature struct {
 	Kind      string
 	Type      SignatureType
 	GoPackage string
+	Tag       string // Struct tag of a field, without the quotes
+	Doc       string
 }
 
 type SignatureType int
@@ -241,13 +399,15 @@ type SignatureType int
 const (
 	SignatureTypeUnknown SignatureType = 0
 	SignatureTypeSingle  SignatureType = 1
-	SignatureTypeSlice   SignatureType = 2
 )
 
 type FunctionSignature struct {
-	Name    string
-	Params  []TypeSignature
-	Results []TypeSignature
+	Name        string
+	Params      []TypeSignature
+	Results     []TypeSignature
+	Annotations map[string]string // Values of //gobridge:<key> <value> comments on the method
+	Doc         string
+	Pos         token.Position
 }
 
 // ListInterfaceMethods returns the function signatures of all the interface methods
@@ -263,72 +423,188 @@ func (r *Reader) ListInterfaceMethods(it *ast.InterfaceType) []FunctionSignature
 
 		sig := r.CheckFunctionSignature(fn)
 		sig.Name = method.Names[0].Name
+		sig.Pos = r.fset.Position(method.Pos())
+		sig.Doc = method.Doc.Text()
+		sig.Annotations = parseAnnotations(method.Doc)
+		r.renameResults(method, &sig)
 		fsSlice = append(fsSlice, sig)
 	}
 
 	return fsSlice
 }
 
-func (r *Reader) CheckFunctionSignature(fn *ast.FuncType) FunctionSignature {
-	var fs FunctionSignature
+// renameResults names the results of the method after its //gobridge:name
+// annotation, which lists a name for each result but the error. It lets
+// unnamed results be given a JSON name without naming the error too.
+func (r *Reader) renameResults(method *ast.Field, sig *FunctionSignature) {
+	v, ok := sig.Annotations["name"]
+	if !ok {
+		return
+	}
 
-	for _, param := range fn.Params.List {
-		n, t := r.parseVariables(param)
-		if strings.Contains(strings.ToLower(t), "context") ||
-			strings.Contains(strings.ToLower(t), "error") {
+	names := strings.Fields(strings.ReplaceAll(v, ",", " "))
+	if len(names) != len(sig.Results) {
+		r.errs = append(r.errs, r.errorf(method.Pos(), "//gobridge:name of %s lists %d names for %d results", sig.Name, len(names), len(sig.Results)))
+		return
+	}
+
+	seen := make(map[string]bool)
+	for i, n := range names {
+		if !token.IsIdentifier(n) || n == "_" {
+			r.errs = append(r.errs, r.errorf(method.Pos(), "//gobridge:name of %s: %q is not a valid name", sig.Name, n))
+			return
+		}
+
+		if seen[strings.ToLower(n)] {
+			r.errs = append(r.errs, r.errorf(method.Pos(), "//gobridge:name of %s: %q is used more than once", sig.Name, n))
+			return
+		}
+		seen[strings.ToLower(n)] = true
+
+		sig.Results[i].Name = n
+	}
+}
+
+// checkMethods reports the interface methods which can't be served over HTTP,
+// as the handlers pass a context and return an error.
+func (r *Reader) checkMethods(it *ast.InterfaceType) []error {
+	var errs []error
+	for _, method := range it.Methods.List {
+		fn, ok := method.Type.(*ast.FuncType)
+		if !ok {
 			continue
 		}
 
-		if strings.HasPrefix(n, "[]") {
-			n = "list"
+		params := fn.Params.List
+		if len(params) == 0 || r.importTypeFromASTExpr(params[0].Type) != "context.Context" {
+			errs = append(errs, r.errorf(method.Pos(), "method %s must take a context.Context as its first parameter", method.Names[0].Name))
 		}
 
-		typ := SignatureTypeSingle
-		if strings.HasPrefix(t, "[]") {
-			typ = SignatureTypeSlice
-			t = strings.ReplaceAll(t, "[]", "")
+		var results []*ast.Field
+		if fn.Results != nil {
+			results = fn.Results.List
+		}
+		if len(results) == 0 || r.importTypeFromASTExpr(results[len(results)-1].Type) != "error" {
+			errs = append(errs, r.errorf(method.Pos(), "method %s must return an error as its last result", method.Names[0].Name))
 		}
 
-		fs.Params = append(fs.Params, TypeSignature{
-			Name: n,
-			Kind: t,
-			Type: typ,
-		})
+		for _, p := range append(params, results...) {
+			if r.importTypeFromASTExpr(p.Type) == "" {
+				errs = append(errs, r.errorf(p.Pos(), "method %s has a parameter or result of unsupported type", method.Names[0].Name))
+			}
+		}
 	}
 
-	for _, result := range fn.Results.List {
-		n, v := r.parseVariables(result)
-		if strings.Contains(strings.ToLower(v), "context") ||
-			strings.Contains(strings.ToLower(v), "error") {
+	return errs
+}
+
+const annotationPrefix = "//gobridge:"
+
+// parseAnnotations collects the //gobridge:<key> <value> directives from a
+// comment group.
+func parseAnnotations(cg *ast.CommentGroup) map[string]string {
+	a := make(map[string]string)
+	if cg == nil {
+		return a
+	}
+
+	for _, c := range cg.List {
+		if !strings.HasPrefix(c.Text, annotationPrefix) {
 			continue
 		}
 
-		if strings.HasPrefix(n, "[]") {
-			n = "list"
+		kv := strings.SplitN(strings.TrimPrefix(c.Text, annotationPrefix), " ", 2)
+		if len(kv) == 1 {
+			a[kv[0]] = ""
+			continue
 		}
 
-		typ := SignatureTypeSingle
-		if strings.HasPrefix(v, "[]") {
-			typ = SignatureTypeSlice
-			v = strings.ReplaceAll(v, "[]", "")
-		}
+		a[kv[0]] = strings.TrimSpace(kv[1])
+	}
 
-		fs.Results = append(fs.Results, TypeSignature{
-			Name: n,
-			Kind: v,
-			Type: typ,
-		})
+	return a
+}
+
+func (r *Reader) CheckFunctionSignature(fn *ast.FuncType) FunctionSignature {
+	var fs FunctionSignature
+
+	for _, param := range fn.Params.List {
+		fs.Params = append(fs.Params, r.parseVariables(param)...)
+	}
+	uniqueNames(fs.Params)
+
+	if fn.Results == nil {
+		return fs
+	}
+
+	for _, result := range fn.Results.List {
+		fs.Results = append(fs.Results, r.parseVariables(result)...)
 	}
+	uniqueNames(fs.Results)
 
 	return fs
 }
 
-func (r *Reader) parseVariables(field *ast.Field) (name string, importTyp string) {
-	if len(field.Names) < 1 {
-		return r.importTypeFromASTExpr(field.Type), r.importTypeFromASTExpr(field.Type)
+// uniqueNames numbers the names shared by unnamed parameters or results of
+// the same type, like "int" and "int2" for (int, int, error).
+func uniqueNames(ts []TypeSignature) {
+	seen := make(map[string]bool)
+	for i, t := range ts {
+		name := t.Name
+		for n := 2; seen[strings.ToLower(name)]; n++ {
+			name = t.Name + strconv.Itoa(n)
+		}
+
+		seen[strings.ToLower(name)] = true
+		ts[i].Name = name
+	}
+}
+
+// unnamed returns the name of an unnamed parameter or result of the type: the
+// type name without its package, "list" for slices and "dict" for maps.
+func unnamed(typ string) string {
+	typ = strings.TrimLeft(typ, "*")
+	switch {
+	case strings.HasPrefix(typ, "["):
+		return "list"
+	case strings.HasPrefix(typ, "map["):
+		return "dict"
+	}
+
+	return typ[strings.LastIndex(typ, ".")+1:]
+}
+
+// parseVariables returns a signature for each name of a parameter or result
+// list entry, like "a, b int", skipping the context and error. Unnamed
+// entries are named after their type.
+func (r *Reader) parseVariables(field *ast.Field) []TypeSignature {
+	t := r.importTypeFromASTExpr(field.Type)
+	if t == "context.Context" || t == "error" {
+		return nil
+	}
+
+	var names []string
+	for _, n := range field.Names {
+		if n.Name == "_" {
+			names = append(names, unnamed(t))
+		} else {
+			names = append(names, n.Name)
+		}
+	}
+	if len(names) == 0 {
+		names = []string{unnamed(t)}
 	}
 
-	return field.Names[0].Name, r.importTypeFromASTExpr(field.Type)
+	var res []TypeSignature
+	for _, n := range names {
+		res = append(res, TypeSignature{
+			Name: n,
+			Kind: t,
+			Type: SignatureTypeSingle,
+		})
+	}
+
+	return res
 }
 
 func (r *Reader) importTypeFromASTExpr(expr ast.Expr) string {
@@ -338,7 +614,12 @@ func (r *Reader) importTypeFromASTExpr(expr ast.Expr) string {
 	case *ast.SelectorExpr:
 		return importTypeFromASTIdent(s.X.(*ast.Ident)) + "." + importTypeFromASTIdent(s.Sel)
 	case *ast.ArrayType:
+		if lit, ok := s.Len.(*ast.BasicLit); ok {
+			return "[" + lit.Value + "]" + r.importTypeFromASTExpr(s.Elt)
+		}
 		return "[]" + r.importTypeFromASTExpr(s.Elt)
+	case *ast.StarExpr:
+		return "*" + r.importTypeFromASTExpr(s.X)
 	case *ast.MapType:
 		p1 := r.importTypeFromASTExpr(s.Key)
 		p2 := r.importTypeFromASTExpr(s.Value)
@@ -348,6 +629,49 @@ func (r *Reader) importTypeFromASTExpr(expr ast.Expr) string {
 	}
 }
 
+// SplitKind separates the pointer and array modifiers of a kind from the
+// type they apply to, e.g. "*[]big.Int" gives "*[]" and "big.Int".
+func SplitKind(kind string) (modifiers string, base string) {
+	i := 0
+	for i < len(kind) {
+		if kind[i] == '*' {
+			i++
+			continue
+		}
+
+		if kind[i] == '[' {
+			end := strings.Index(kind[i:], "]")
+			if end < 0 {
+				break
+			}
+			i += end + 1
+			continue
+		}
+
+		break
+	}
+
+	return kind[:i], kind[i:]
+}
+
+// ImportName returns the package name of an import path by convention, the
+// last path element without any gopkg.in style version suffix.
+func ImportName(path string) string {
+	elems := strings.Split(path, "/")
+	name := elems[len(elems)-1]
+
+	// Skip major version suffixes like /v3
+	if len(elems) > 1 && len(name) > 1 && name[0] == 'v' && strings.Trim(name[1:], "0123456789") == "" {
+		name = elems[len(elems)-2]
+	}
+
+	if i := strings.Index(name, ".v"); i > 0 {
+		name = name[:i]
+	}
+
+	return name
+}
+
 // importTypeFromASTIdent collects the type name and can be used for non-import types in the file tree
 func importTypeFromASTIdent(ident *ast.Ident) string {
 	return ident.Name
@@ -0,0 +1,287 @@
+package templates
+
+import (
+	"os"
+	"text/template"
+)
+
+// ContractTest is the data of the contract template, a Go test which round
+// trips random parameters and results of every method through the generated
+// client and server. The template is also passed StdImports and Imports, the
+// standard library and other packages imported.
+type ContractTest struct {
+	Package  string // Name of the test package
+	API      string // Qualified API interface
+	Imports  []string
+	Server   string // Name of the server package
+	Client   string // Name of the client package
+	Handlers []HTTPHandler
+}
+
+// contractStdImports are imported by every generated contract test.
+var contractStdImports = []string{
+	"bytes",
+	"context",
+	"encoding",
+	"encoding/json",
+	"math/rand",
+	"net/http",
+	"net/http/httptest",
+	"reflect",
+	"sync",
+	"testing",
+	"time",
+}
+
+func (ct *ContractTest) AddTo(file *os.File, o Overrides) error {
+	std, other := importGroups(contractStdImports, ct.Imports)
+	data := struct {
+		*ContractTest
+		StdImports []string
+		Imports    []string
+	}{
+		ContractTest: ct,
+		StdImports:   std,
+		Imports:      other,
+	}
+
+	t := template.Must(template.New("contract").Funcs(funcs(nil)).Parse(contractTemplate))
+	t, err := o.apply(t)
+	if err != nil {
+		return err
+	}
+
+	return executeGo(file, t, data)
+}
+
+var contractTemplate = `// Code generated by gobridge; DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+{{- range .StdImports }}
+	"{{.}}"
+{{- end }}
+{{ range .Imports }}
+	"{{.}}"
+{{- end }}
+)
+
+// contractRounds is the number of random values sent to each method.
+const contractRounds = 20
+
+// TestContract round trips random values through the generated client and
+// server, returning random results from the API.
+func TestContract(t *testing.T) {
+	testContract(t, &randomAPI{rand: rand.New(rand.NewSource(1))})
+}
+
+// testContract serves impl with the generated handlers and calls each method
+// with random parameters through the generated client. It fails when impl
+// doesn't receive the parameters the client sent, or the client doesn't
+// return the results impl returned, which catches values lost or changed by
+// the JSON encoding between them.
+func testContract(t *testing.T, impl {{.API}}) {
+	rec := &recorder{impl: impl}
+
+	mux := http.NewServeMux()
+{{- range .Handlers }}
+	mux.HandleFunc({{$.Server}}.{{.Method}}Endpoint.Path(), {{$.Server}}.Handle{{.Method}}(rec))
+{{- end }}
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	c := {{.Client}}.NewClient(srv.URL)
+	rnd := rand.New(rand.NewSource(1))
+{{ range .Handlers }}
+	t.Run("{{.Method}}", func(t *testing.T) {
+		for i := 0; i < contractRounds; i++ {
+			{{- if .Types.Request }}
+			var (
+			{{- range $i, $f := .Types.Request }}
+				p{{$i}} {{$f.Type}}
+			{{- end }}
+			)
+			randomize(rnd{{ range $i, $f := .Types.Request }}, &p{{$i}}{{ end }})
+			{{- end }}
+
+			{{ range $i, $f := .Types.Response }}r{{$i}}, {{ end }}err := c.{{.Method}}(context.Background(){{ range $i, $f := .Types.Request }}, p{{$i}}{{ end }})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			params, results := rec.last()
+			assertSame(t, "parameters", []interface{}{ {{- range $i, $f := .Types.Request }}p{{$i}}, {{ end -}} }, params)
+			assertSame(t, "results", results, []interface{}{ {{- range $i, $f := .Types.Response }}r{{$i}}, {{ end -}} })
+		}
+	})
+{{ end -}}
+}
+
+// recorder records the parameters and results of the calls to impl.
+type recorder struct {
+	impl {{.API}}
+
+	mu      sync.Mutex
+	params  []interface{}
+	results []interface{}
+}
+
+func (rec *recorder) last() (params []interface{}, results []interface{}) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	return rec.params, rec.results
+}
+{{ range .Handlers }}
+func (rec *recorder) {{.Method}}(ctx context.Context{{ range $i, $f := .Types.Request }}, p{{$i}} {{$f.Type}}{{ end }}) ({{ range .Types.Response }}{{.Type}}, {{ end }}error) {
+	{{ range $i, $f := .Types.Response }}r{{$i}}, {{ end }}err := rec.impl.{{.Method}}(ctx{{ range $i, $f := .Types.Request }}, p{{$i}}{{ end }})
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	rec.params = []interface{}{ {{- range $i, $f := .Types.Request }}p{{$i}}, {{ end -}} }
+	rec.results = []interface{}{ {{- range $i, $f := .Types.Response }}r{{$i}}, {{ end -}} }
+
+	return {{ range $i, $f := .Types.Response }}r{{$i}}, {{ end }}err
+}
+{{ end }}
+// randomAPI returns random results.
+type randomAPI struct {
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+{{ range .Handlers }}
+func (a *randomAPI) {{.Method}}(ctx context.Context{{ range $i, $f := .Types.Request }}, p{{$i}} {{$f.Type}}{{ end }}) ({{ range $i, $f := .Types.Response }}r{{$i}} {{$f.Type}}, {{ end }}err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	randomize(a.rand{{ range $i, $f := .Types.Response }}, &r{{$i}}{{ end }})
+	return {{ range $i, $f := .Types.Response }}r{{$i}}, {{ end }}nil
+}
+{{ end }}
+// assertSame compares values by their JSON encoding, as the generated code
+// only needs to keep what is encoded, such as the instant of a time but not
+// its location.
+func assertSame(t *testing.T, what string, want, got []interface{}) {
+	t.Helper()
+
+	w, err := json.Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g, err := json.Marshal(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(w, g) {
+		t.Errorf("%s changed:\nsent:     %s\nreceived: %s", what, w, g)
+	}
+}
+
+// maxDepth limits the nesting of random values, so recursive types end.
+const maxDepth = 3
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	marshalerType     = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// randomize sets the values pointed to by ptrs to random values that can be
+// encoded as JSON.
+func randomize(r *rand.Rand, ptrs ...interface{}) {
+	for _, p := range ptrs {
+		randomValue(r, reflect.ValueOf(p).Elem(), 0)
+	}
+}
+
+func randomValue(r *rand.Rand, v reflect.Value, depth int) {
+	typ := v.Type()
+	if typ == timeType {
+		v.Set(reflect.ValueOf(time.Unix(r.Int63n(1<<33), r.Int63n(1e9)).UTC()))
+		return
+	}
+
+	// Types with their own encoding, like big.Int, are left as the zero value
+	// as their fields don't say which values are valid.
+	for _, m := range []reflect.Type{marshalerType, textMarshalerType} {
+		if typ.Implements(m) || reflect.PtrTo(typ).Implements(m) {
+			return
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		v.SetBool(r.Intn(2) == 1)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(int64(r.Uint64()) >> (64 - typ.Bits()))
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		v.SetUint(r.Uint64() >> (64 - typ.Bits()))
+
+	case reflect.Float32:
+		v.SetFloat(float64(float32(r.NormFloat64() * 1e6)))
+
+	case reflect.Float64:
+		v.SetFloat(r.NormFloat64() * 1e6)
+
+	case reflect.String:
+		const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789 _-é€😀"
+		runes := []rune(letters)
+		s := make([]rune, r.Intn(12))
+		for i := range s {
+			s[i] = runes[r.Intn(len(runes))]
+		}
+		v.SetString(string(s))
+
+	case reflect.Ptr:
+		if depth >= maxDepth || r.Intn(4) == 0 {
+			return
+		}
+		v.Set(reflect.New(typ.Elem()))
+		randomValue(r, v.Elem(), depth+1)
+
+	case reflect.Slice:
+		if depth >= maxDepth || r.Intn(4) == 0 {
+			return
+		}
+		n := r.Intn(4)
+		v.Set(reflect.MakeSlice(typ, n, n))
+		for i := 0; i < n; i++ {
+			randomValue(r, v.Index(i), depth+1)
+		}
+
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			randomValue(r, v.Index(i), depth+1)
+		}
+
+	case reflect.Map:
+		if depth >= maxDepth || r.Intn(4) == 0 {
+			return
+		}
+		v.Set(reflect.MakeMap(typ))
+		for i := r.Intn(4); i > 0; i-- {
+			key := reflect.New(typ.Key()).Elem()
+			randomValue(r, key, depth+1)
+			elem := reflect.New(typ.Elem()).Elem()
+			randomValue(r, elem, depth+1)
+			v.SetMapIndex(key, elem)
+		}
+
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			f := typ.Field(i)
+			if f.PkgPath != "" || f.Tag.Get("json") == "-" {
+				continue
+			}
+			randomValue(r, v.Field(i), depth+1)
+		}
+	}
+}
+`
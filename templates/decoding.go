@@ -0,0 +1,180 @@
+package templates
+
+// decodingTemplate is rendered into the generated server and decodes request
+// bodies either leniently or strictly, depending on the DecodeMode.
+var decodingTemplate = `{{define "decoding"}}
+// DecodeMode controls how request bodies are decoded.
+type DecodeMode int
+
+const (
+	// DecodeLenient ignores unknown fields, matching json.Unmarshal.
+	DecodeLenient DecodeMode = 0
+
+	// DecodeStrict rejects unknown fields and trailing data, responding with
+	// a DecodeError listing the offending fields.
+	DecodeStrict DecodeMode = 1
+)
+
+// WithDecodeMode sets how request bodies are decoded. The default is DecodeLenient.
+func WithDecodeMode(m DecodeMode) Option {
+	return func(s *Server) {
+		s.DecodeMode = m
+	}
+}
+
+type decodeModeKey struct{}
+
+// DecodeError is the JSON body of a 400 response to a request that could not
+// be decoded in strict mode.
+type DecodeError struct {
+	Message string   ` + "`json:\"message\"`" + `
+	Fields  []string ` + "`json:\"fields,omitempty\"`" + `
+}
+
+func (e *DecodeError) Error() string {
+	if len(e.Fields) == 0 {
+		return e.Message
+	}
+
+	return e.Message + ": " + strings.Join(e.Fields, ", ")
+}
+
+// decodeRequest decodes the request body into v using the DecodeMode set on
+// the context by Server.Wrap.
+func decodeRequest(ctx context.Context, b []byte, v interface{}) error {
+	if int64AsString {
+		b = convertInt64(b, reflect.TypeOf(v), false)
+	}
+
+	mode, _ := ctx.Value(decodeModeKey{}).(DecodeMode)
+	if mode != DecodeStrict {
+		return json.Unmarshal(b, v)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+
+	err := dec.Decode(v)
+	if err != nil {
+		return newDecodeError(b, v, err)
+	}
+
+	if _, err := dec.Token(); err != io.EOF {
+		return &DecodeError{Message: "unexpected data after request body"}
+	}
+
+	return nil
+}
+
+func newDecodeError(b []byte, v interface{}, err error) *DecodeError {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return &DecodeError{
+			Message: fmt.Sprintf("invalid value: expected %s but got %s", typeErr.Type, typeErr.Value),
+			Fields:  []string{typeErr.Field},
+		}
+	}
+
+	if strings.HasPrefix(err.Error(), "json: unknown field ") {
+		var raw interface{}
+		if json.Unmarshal(b, &raw) == nil {
+			fields := unknownFields(reflect.TypeOf(v), raw, "")
+			if len(fields) > 0 {
+				return &DecodeError{Message: "unknown fields", Fields: fields}
+			}
+		}
+
+		name := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), ` + "`\"`" + `)
+		return &DecodeError{Message: "unknown fields", Fields: []string{name}}
+	}
+
+	return &DecodeError{Message: err.Error()}
+}
+
+var unmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// unknownFields returns the paths of all the object keys in raw that don't
+// match a field of t.
+func unknownFields(t reflect.Type, raw interface{}, path string) []string {
+	if reflect.PointerTo(t).Implements(unmarshalerType) {
+		return nil
+	}
+
+	var res []string
+	switch t.Kind() {
+	case reflect.Pointer:
+		return unknownFields(t.Elem(), raw, path)
+	case reflect.Slice, reflect.Array:
+		l, _ := raw.([]interface{})
+		for i, v := range l {
+			res = append(res, unknownFields(t.Elem(), v, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	case reflect.Map:
+		m, _ := raw.(map[string]interface{})
+		for k, v := range m {
+			res = append(res, unknownFields(t.Elem(), v, fmt.Sprintf("%s[%s]", path, k))...)
+		}
+	case reflect.Struct:
+		m, _ := raw.(map[string]interface{})
+		fields := jsonFields(t)
+		for k, v := range m {
+			p := k
+			if path != "" {
+				p = path + "." + k
+			}
+
+			f, ok := fields[strings.ToLower(k)]
+			if !ok {
+				res = append(res, p)
+				continue
+			}
+
+			res = append(res, unknownFields(f.typ, v, p)...)
+		}
+	}
+
+	sort.Strings(res)
+	return res
+}
+
+type jsonField struct {
+	typ    reflect.Type
+	quoted bool // Set by the ",string" tag option
+}
+
+// jsonFields returns the fields of struct t keyed by their lowercased JSON names.
+func jsonFields(t reflect.Type) map[string]jsonField {
+	fields := make(map[string]jsonField)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				for n, typ := range jsonFields(ft) {
+					fields[n] = typ
+				}
+				continue
+			}
+		}
+
+		if !f.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+		fields[strings.ToLower(name)] = jsonField{typ: f.Type, quoted: strings.Contains(","+opts+",", ",string,")}
+	}
+
+	return fields
+}
+{{end}}`
@@ -0,0 +1,55 @@
+package templates
+
+import "strings"
+
+// GoDoc formats a doc comment as Go line comments, ending with a newline so
+// it can precede a declaration. It returns an empty string for an empty doc.
+func GoDoc(doc string) string {
+	doc = strings.TrimSpace(doc)
+	if doc == "" {
+		return ""
+	}
+
+	var b strings.Builder
+	for _, line := range strings.Split(doc, "\n") {
+		b.WriteString(strings.TrimRight("// "+line, " "))
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
+
+// JSDoc formats a doc comment as a JSDoc block indented by indent, ending with
+// a newline. It returns an empty string for an empty doc.
+func JSDoc(indent, doc string) string {
+	doc = strings.TrimSpace(strings.ReplaceAll(doc, "*/", "*\\/"))
+	if doc == "" {
+		return ""
+	}
+
+	lines := strings.Split(doc, "\n")
+	if len(lines) == 1 {
+		return indent + "/** " + jsDocTag(lines[0]) + " */\n"
+	}
+
+	var b strings.Builder
+	b.WriteString(indent + "/**\n")
+	for _, line := range lines {
+		line = jsDocTag(line)
+		b.WriteString(strings.TrimRight(indent+" * "+line, " "))
+		b.WriteString("\n")
+	}
+	b.WriteString(indent + " */\n")
+
+	return b.String()
+}
+
+// jsDocTag turns the start of a Go "Deprecated: " paragraph into the JSDoc
+// @deprecated tag understood by editors.
+func jsDocTag(line string) string {
+	if strings.HasPrefix(line, "Deprecated: ") {
+		return "@deprecated " + strings.TrimPrefix(line, "Deprecated: ")
+	}
+
+	return line
+}
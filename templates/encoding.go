@@ -0,0 +1,134 @@
+package templates
+
+// encodingTemplate is rendered into the generated server and provides the
+// JSON encoding shared by the server and the Go client.
+var encodingTemplate = `{{define "encoding"}}
+// int64AsString is set when the API was generated with int64 and uint64
+// values encoded as JSON strings, as JavaScript numbers can't represent them
+// exactly above 2^53.
+const int64AsString = {{.Int64AsString}}
+
+// EncodeJSON encodes v the way the server encodes responses.
+func EncodeJSON(v interface{}) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil || !int64AsString {
+		return b, err
+	}
+
+	return convertInt64(b, reflect.TypeOf(v), true), nil
+}
+
+// DecodeJSON decodes JSON produced by EncodeJSON into v.
+func DecodeJSON(b []byte, v interface{}) error {
+	if int64AsString {
+		b = convertInt64(b, reflect.TypeOf(v), false)
+	}
+
+	return json.Unmarshal(b, v)
+}
+
+var (
+	durationType      = reflect.TypeOf(time.Duration(0))
+	marshalerType     = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// isInt64 reports whether t is encoded as a 64-bit integer, including named
+// types like "type UserID int64". time.Duration is left as a number for the
+// clients to convert, and types with their own encoding are left alone.
+func isInt64(t reflect.Type) bool {
+	if t.Kind() != reflect.Int64 && t.Kind() != reflect.Uint64 {
+		return false
+	}
+
+	if t == durationType {
+		return false
+	}
+
+	for _, m := range []reflect.Type{marshalerType, textMarshalerType} {
+		if t.Implements(m) || reflect.PointerTo(t).Implements(m) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// convertInt64 rewrites the int64 and uint64 values of the JSON encoding of
+// t to strings, or back to numbers when toString is false. Invalid input is
+// returned unchanged for the decoder to report.
+func convertInt64(b []byte, t reflect.Type, toString bool) []byte {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+
+	var raw interface{}
+	if err := dec.Decode(&raw); err != nil {
+		return b
+	}
+
+	if _, err := dec.Token(); err != io.EOF {
+		return b
+	}
+
+	res, err := json.Marshal(walkInt64(t, raw, toString))
+	if err != nil {
+		return b
+	}
+
+	return res
+}
+
+func walkInt64(t reflect.Type, raw interface{}, toString bool) interface{} {
+	if t == nil || reflect.PointerTo(t).Implements(unmarshalerType) {
+		return raw
+	}
+
+	if isInt64(t) {
+		switch v := raw.(type) {
+		case json.Number:
+			if toString {
+				return v.String()
+			}
+		case string:
+			if !toString {
+				if _, err := strconv.ParseInt(v, 10, 64); err == nil {
+					return json.Number(v)
+				}
+				if _, err := strconv.ParseUint(v, 10, 64); err == nil {
+					return json.Number(v)
+				}
+			}
+		}
+
+		return raw
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return walkInt64(t.Elem(), raw, toString)
+	case reflect.Slice, reflect.Array:
+		l, _ := raw.([]interface{})
+		for i, v := range l {
+			l[i] = walkInt64(t.Elem(), v, toString)
+		}
+	case reflect.Map:
+		m, _ := raw.(map[string]interface{})
+		for k, v := range m {
+			m[k] = walkInt64(t.Elem(), v, toString)
+		}
+	case reflect.Struct:
+		m, _ := raw.(map[string]interface{})
+		fields := jsonFields(t)
+		for k, v := range m {
+			f, ok := fields[strings.ToLower(k)]
+			if !ok || f.quoted {
+				continue
+			}
+
+			m[k] = walkInt64(f.typ, v, toString)
+		}
+	}
+
+	return raw
+}
+{{end}}`
@@ -0,0 +1,152 @@
+package templates
+
+import (
+	"os"
+	"text/template"
+)
+
+// GoFake is the data of the fake template, configurable fakes of the API
+// interfaces for tests. The template is also passed StdImports and Imports,
+// the standard library and other packages imported.
+type GoFake struct {
+	Package string // Name of the fake package
+	Imports []string
+	Fakes   []Fake
+}
+
+// Fake is the fake of an API interface.
+type Fake struct {
+	Name    string // Name of the interface
+	API     string // Qualified interface
+	Methods []FakeMethod
+}
+
+// FakeMethod is a method of a fake, with parameters named by their Arg, the
+// data of the method template.
+type FakeMethod struct {
+	Fake    string // Name of the interface
+	Name    string
+	Params  []Field
+	Results []Field
+}
+
+// fakeStdImports are imported by every generated fake.
+var fakeStdImports = []string{
+	"context",
+	"sync",
+}
+
+func (f *GoFake) AddTo(file *os.File, o Overrides) error {
+	std, other := importGroups(fakeStdImports, f.Imports)
+	data := struct {
+		*GoFake
+		StdImports []string
+		Imports    []string
+	}{
+		GoFake:     f,
+		StdImports: std,
+		Imports:    other,
+	}
+
+	t := template.Must(template.New("fake").Funcs(funcs(nil)).Parse(fakeTemplate + fakeMethodTemplate))
+	t, err := o.apply(t)
+	if err != nil {
+		return err
+	}
+
+	return executeGo(file, t, data)
+}
+
+var fakeTemplate = `// Code generated by gobridge; DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+{{- range .StdImports }}
+	"{{.}}"
+{{- end }}
+{{ range .Imports }}
+	"{{.}}"
+{{- end }}
+)
+{{ range .Fakes }}
+{{- $fake := . }}
+
+// Fake{{.Name}} is a configurable fake of {{.API}} for tests. Each method
+// records its call and calls the func field of the same name when set, or
+// returns the results set by its Returns method, which default to the zero
+// values and a nil error. It's safe for concurrent use.
+type Fake{{.Name}} struct {
+{{- range .Methods }}
+	{{.Name}}Func func(ctx context.Context{{ range .Params }}, {{.Arg}} {{.Type}}{{ end }}) ({{ range .Results }}{{.Type}}, {{ end }}error)
+{{- end }}
+
+	mu sync.Mutex
+{{- range .Methods }}
+	calls{{.Name}}   []fake{{$fake.Name}}{{.Name}}Call
+	returns{{.Name}} fake{{$fake.Name}}{{.Name}}Results
+{{- end }}
+}
+
+var _ {{.API}} = (*Fake{{.Name}})(nil)
+{{ range .Methods }}
+{{ template "method" . }}
+{{ end }}
+{{- end }}`
+
+// fakeMethodTemplate is executed for each FakeMethod.
+var fakeMethodTemplate = `{{define "method"}}
+{{- $name := printf "fake%s%s" .Fake .Name }}
+type {{$name}}Call struct {
+{{- range .Params }}
+	{{.Arg}} {{.Type}}
+{{- end }}
+}
+
+type {{$name}}Results struct {
+{{- range $i, $r := .Results }}
+	r{{$i}} {{$r.Type}}
+{{- end }}
+	err error
+}
+
+func (f *Fake{{.Fake}}) {{.Name}}(ctx context.Context{{ range .Params }}, {{.Arg}} {{.Type}}{{ end }}) ({{ range .Results }}{{.Type}}, {{ end }}error) {
+	f.mu.Lock()
+	f.calls{{.Name}} = append(f.calls{{.Name}}, {{$name}}Call{ {{- range $i, $p := .Params }}{{if $i}}, {{end}}{{$p.Arg}}{{ end -}} })
+	fn, ret := f.{{.Name}}Func, f.returns{{.Name}}
+	f.mu.Unlock()
+
+	if fn != nil {
+		return fn(ctx{{ range .Params }}, {{.Arg}}{{ end }})
+	}
+
+	return {{ range $i, $r := .Results }}ret.r{{$i}}, {{ end }}ret.err
+}
+
+// {{.Name}}Returns sets the results returned by {{.Name}} when {{.Name}}Func isn't set.
+func (f *Fake{{.Fake}}) {{.Name}}Returns({{ range $i, $r := .Results }}r{{$i}} {{$r.Type}}, {{ end }}err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.returns{{.Name}} = {{$name}}Results{ {{- range $i, $r := .Results }}r{{$i}}, {{ end }}err}
+}
+
+// {{.Name}}CallCount returns the number of calls to {{.Name}}.
+func (f *Fake{{.Fake}}) {{.Name}}CallCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return len(f.calls{{.Name}})
+}
+{{- if .Params }}
+
+// {{.Name}}ArgsForCall returns the arguments of the i-th call to {{.Name}}, counting from 0.
+func (f *Fake{{.Fake}}) {{.Name}}ArgsForCall(i int) ({{ range $i, $p := .Params }}{{if $i}}, {{end}}{{$p.Type}}{{ end }}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c := f.calls{{.Name}}[i]
+	return {{ range $i, $p := .Params }}{{if $i}}, {{end}}c.{{$p.Arg}}{{ end }}
+}
+{{- end }}
+{{- end}}`
@@ -5,58 +5,175 @@ import (
 	"text/template"
 )
 
-type HttpClient struct {
-	Service string
+// GoClient is the data of the client template. The template is also passed
+// StdImports and Imports, the standard library and other packages imported.
+type GoClient struct {
+	Package  string // Name of the client package
+	API      string // Qualified API interface
+	Imports  []string
+	TypesPkg string // Package of the generated server types, empty when generated alongside the server
+	Handlers []HTTPHandler
+}
+
+// clientStdImports are imported by every generated client.
+var clientStdImports = []string{
+	"bytes",
+	"context",
+	"fmt",
+	"io/ioutil",
+	"net/http",
+	"strings",
+}
+
+func (cl *GoClient) AddTo(file *os.File, o Overrides) error {
+	std, other := importGroups(clientStdImports, cl.Imports)
+	data := struct {
+		*GoClient
+		StdImports []string
+		Imports    []string
+	}{
+		GoClient:   cl,
+		StdImports: std,
+		Imports:    other,
+	}
+
+	qualifier := ""
+	if cl.TypesPkg != "" {
+		qualifier = cl.TypesPkg + "."
+	}
+
+	t := template.Must(template.New("client").Funcs(funcs(template.FuncMap{
+		"Types": func(name string) string {
+			return qualifier + name
+		},
+	})).Parse(httpClientTemplate + clientMethodTemplate))
+	t, err := o.apply(t)
+	if err != nil {
+		return err
+	}
 
-	Type    string
-	Method  string
-	Params  []string
-	Results []string
+	return executeGo(file, t, data)
+}
+
+var httpClientTemplate = `// Code generated by gobridge; DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+{{- range $key, $value := .StdImports }}
+	"{{$value}}"
+{{- end }}
+{{ range $key, $value := .Imports }}
+	"{{$value}}"
+{{- end }}
+)
 
-	RequestType string
-	Request     map[string]string
+// Client calls the {{.API}} API over HTTP.
+type Client struct {
+	Address       string
+	HttpClient    *http.Client
+	Authorization string
+	Tracer        {{Types "Tracer"}}
+}
 
-	ResponseType   string
-	ResponseParams []string
+// ClientOption configures optional behaviour of the Client.
+type ClientOption func(c *Client)
 
-	Return []string
+// WithHTTPClient sets the http.Client used to make requests.
+func WithHTTPClient(hc *http.Client) ClientOption {
+	return func(c *Client) {
+		c.HttpClient = hc
+	}
 }
 
-func (cl *HttpClient) AddTo(file *os.File) error {
-	return template.Must(template.New("").Parse(httpClientTemplate)).Execute(file, cl)
+// WithAuthorization sets the Authorization header sent with every request.
+func WithAuthorization(token string) ClientOption {
+	return func(c *Client) {
+		c.Authorization = token
+	}
 }
 
-var httpClientTemplate = `
-func (c * Client) {{.Method}}(ctx context.Context{{ range $key, $value := .Params }}, {{ $value }}{{ end }}) ({{- range $key, $value := .Results }}{{if $key}}, {{end}}{{ $value }}{{- end }}) {
-	req := {{.RequestType}} {
-	{{- range $key, $value := .Request }}
-		{{$key}}: {{$value}},
-	{{- end}}
+// WithClientTracer sets the Tracer used to inject trace context into requests.
+func WithClientTracer(t {{Types "Tracer"}}) ClientOption {
+	return func(c *Client) {
+		c.Tracer = t
 	}
+}
 
-	b, err := json.Marshal(req)
+func NewClient(address string, opts ...ClientOption) *Client {
+	c := &Client{
+		Address:    strings.TrimSuffix(address, "/"),
+		HttpClient: http.DefaultClient,
+		Tracer:     {{Types "W3CTracer"}}{},
+	}
+
+	for _, o := range opts {
+		o(c)
+	}
+
+	return c
+}
+
+func (c *Client) call(ctx context.Context, path string, req interface{}, resp interface{}) error {
+	b, err := {{Types "EncodeJSON"}}(req)
 	if err != nil {
-		return {{ range $key, $value := .Return }}{{if $key}}, {{end}}{{ $value }}{{ end }}
+		return err
 	}
 
-	uniquePath := "/{{.Service}}/{{.Method}}"
-	buf := bytes.NewBuffer(b)
-	httpResp, err := ctxhttp.Post(ctx, c.HttpClient, c.Address + uniquePath, "application/json", buf)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.Address+path, bytes.NewReader(b))
 	if err != nil {
-		return {{ range $key, $value := .Return }}{{if $key}}, {{end}}{{ $value }}{{ end }}
+		return err
 	}
 
-	respBody, err := ioutil.ReadAll(httpResp.Body)
+	httpReq.Header.Set("Content-Type", "application/json")
+	if c.Authorization != "" {
+		httpReq.Header.Set("Authorization", c.Authorization)
+	}
+
+	if c.Tracer != nil {
+		c.Tracer.Inject(ctx).SetHeader(httpReq.Header)
+	}
+
+	httpResp, err := c.HttpClient.Do(httpReq)
 	if err != nil {
-		return {{ range $key, $value := .Return }}{{if $key}}, {{end}}{{ $value }}{{ end }}
+		return err
 	}
+	defer httpResp.Body.Close()
 
-	var resp {{.ResponseType}}
-	err = json.Unmarshal(respBody, &resp)
+	respBody, err := ioutil.ReadAll(httpResp.Body)
 	if err != nil {
-		return {{ range $key, $value := .Return }}{{if $key}}, {{end}}{{ $value }}{{ end }}
+		return err
+	}
+
+	if httpResp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", httpResp.Status, strings.TrimSpace(string(respBody)))
+	}
+
+	return {{Types "DecodeJSON"}}(respBody, resp)
+}
+{{ range .Handlers }}
+{{ template "method" . }}
+{{ end }}`
+
+// clientMethodTemplate is executed for each HTTPHandler, calling it from the client.
+var clientMethodTemplate = `{{define "method"}}
+{{- if .Passthrough }}
+{{ GoDoc .Doc }}func (c *Client) {{.Method}}(ctx context.Context, req {{(index .Types.Request 0).Type}}) ({{(index .Types.Response 0).Type}}, error) {
+	var resp {{(index .Types.Response 0).Type}}
+	err := c.call(ctx, "/{{.URL}}", req, &resp)
+	return resp, err
+}
+{{- else }}
+{{ GoDoc .Doc }}func (c *Client) {{.Method}}(ctx context.Context{{ range .Types.Request }}, {{.Arg}} {{.Type}}{{ end }}) ({{ range .Types.Response }}{{.Type}}, {{ end }}error) {
+	req := {{Types .RequestType}}Request{
+	{{- range .Types.Request }}
+		{{.Name | ToCamelCase}}: {{.Arg}},
+	{{- end }}
 	}
 
-	{{ if not (eq (len .ResponseParams) 0)}}return {{range $key, $value := .ResponseParams }}{{if $key}}, {{end}}{{if eq $value "_"}}{{else if $value}}resp.{{end}}{{$value}}{{end}}, nil{{end}}{{ if eq (len .ResponseParams) 0}}return nil{{end}}
+	var resp {{Types .ResponseType}}Response
+	err := c.call(ctx, "/{{.URL}}", req, &resp)
+	return {{ range .Types.Response }}resp.{{.Name | ToCamelCase}}, {{ end }}err
 }
-`
+{{- end }}
+{{end}}`
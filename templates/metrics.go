@@ -0,0 +1,188 @@
+package templates
+
+// metricsTemplate is rendered into the generated server and provides the
+// Observer hooks along with a dependency free Prometheus exporter.
+var metricsTemplate = `{{define "metrics"}}
+// Observer is notified at the start and end of every request handled by the Server.
+type Observer interface {
+	RequestStarted(ctx context.Context, e Endpoint)
+	RequestFinished(ctx context.Context, e Endpoint, info RequestInfo)
+}
+
+// RequestInfo describes a finished request.
+type RequestInfo struct {
+	Status        int
+	Duration      time.Duration
+	RequestBytes  int64
+	ResponseBytes int64
+	ErrorCode     string // Empty for successful requests, otherwise the HTTP status text
+}
+
+// WithObserver adds an Observer that is notified of every request.
+func WithObserver(o Observer) Option {
+	return func(s *Server) {
+		s.Observers = append(s.Observers, o)
+	}
+}
+
+// WithMetrics records request metrics in m and serves them in the Prometheus
+// text format on /metrics. The metrics handler is not wrapped by the auth checks.
+func WithMetrics(m *Metrics) Option {
+	return func(s *Server) {
+		s.Observers = append(s.Observers, m)
+		s.Metrics = m
+	}
+}
+
+func (s *Server) requestStarted(ctx context.Context, e Endpoint) {
+	for _, o := range s.Observers {
+		o.RequestStarted(ctx, e)
+	}
+}
+
+func (s *Server) requestFinished(ctx context.Context, e Endpoint, info RequestInfo) {
+	for _, o := range s.Observers {
+		o.RequestFinished(ctx, e, info)
+	}
+}
+
+// countingReader counts the bytes read from a request body.
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (r *countingReader) Read(b []byte) (int, error) {
+	n, err := r.ReadCloser.Read(b)
+	r.n += int64(n)
+	return n, err
+}
+
+// DurationBuckets are the upper bounds, in seconds, of the request duration histogram.
+var DurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Metrics is an Observer that aggregates per endpoint request metrics and
+// exposes them in the Prometheus text exposition format.
+type Metrics struct {
+	mu            sync.Mutex
+	inFlight      map[Endpoint]int64
+	requests      map[metricsKey]int64
+	durations     map[Endpoint]*histogram
+	requestBytes  map[Endpoint]int64
+	responseBytes map[Endpoint]int64
+}
+
+type metricsKey struct {
+	endpoint Endpoint
+	status   int
+}
+
+type histogram struct {
+	buckets []uint64 // Cumulative counts for each of the DurationBuckets
+	sum     float64
+	count   uint64
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{
+		inFlight:      make(map[Endpoint]int64),
+		requests:      make(map[metricsKey]int64),
+		durations:     make(map[Endpoint]*histogram),
+		requestBytes:  make(map[Endpoint]int64),
+		responseBytes: make(map[Endpoint]int64),
+	}
+}
+
+func (m *Metrics) RequestStarted(_ context.Context, e Endpoint) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.inFlight[e]++
+}
+
+func (m *Metrics) RequestFinished(_ context.Context, e Endpoint, info RequestInfo) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.inFlight[e]--
+	m.requests[metricsKey{endpoint: e, status: info.Status}]++
+	m.requestBytes[e] += info.RequestBytes
+	m.responseBytes[e] += info.ResponseBytes
+
+	h, ok := m.durations[e]
+	if !ok {
+		h = &histogram{buckets: make([]uint64, len(DurationBuckets))}
+		m.durations[e] = h
+	}
+
+	secs := info.Duration.Seconds()
+	for i, bound := range DurationBuckets {
+		if secs <= bound {
+			h.buckets[i]++
+		}
+	}
+	h.sum += secs
+	h.count++
+}
+
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var buf bytes.Buffer
+
+	buf.WriteString("# HELP gobridge_requests_total Total number of requests handled per endpoint and status.\n")
+	buf.WriteString("# TYPE gobridge_requests_total counter\n")
+	keys := make([]metricsKey, 0, len(m.requests))
+	for k := range m.requests {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].endpoint != keys[j].endpoint {
+			return keys[i].endpoint < keys[j].endpoint
+		}
+		return keys[i].status < keys[j].status
+	})
+	for _, k := range keys {
+		fmt.Fprintf(&buf, "gobridge_requests_total{endpoint=%q,status=\"%d\"} %d\n", k.endpoint.Path(), k.status, m.requests[k])
+	}
+
+	buf.WriteString("# HELP gobridge_requests_in_flight Number of requests currently being handled per endpoint.\n")
+	buf.WriteString("# TYPE gobridge_requests_in_flight gauge\n")
+	for e := Endpoint(0); e < AllEndpoints; e++ {
+		fmt.Fprintf(&buf, "gobridge_requests_in_flight{endpoint=%q} %d\n", e.Path(), m.inFlight[e])
+	}
+
+	buf.WriteString("# HELP gobridge_request_duration_seconds Time taken to handle requests per endpoint.\n")
+	buf.WriteString("# TYPE gobridge_request_duration_seconds histogram\n")
+	for e := Endpoint(0); e < AllEndpoints; e++ {
+		h, ok := m.durations[e]
+		if !ok {
+			continue
+		}
+
+		for i, bound := range DurationBuckets {
+			le := strconv.FormatFloat(bound, 'g', -1, 64)
+			fmt.Fprintf(&buf, "gobridge_request_duration_seconds_bucket{endpoint=%q,le=%q} %d\n", e.Path(), le, h.buckets[i])
+		}
+		fmt.Fprintf(&buf, "gobridge_request_duration_seconds_bucket{endpoint=%q,le=\"+Inf\"} %d\n", e.Path(), h.count)
+		fmt.Fprintf(&buf, "gobridge_request_duration_seconds_sum{endpoint=%q} %g\n", e.Path(), h.sum)
+		fmt.Fprintf(&buf, "gobridge_request_duration_seconds_count{endpoint=%q} %d\n", e.Path(), h.count)
+	}
+
+	buf.WriteString("# HELP gobridge_request_size_bytes_total Total size of request bodies per endpoint.\n")
+	buf.WriteString("# TYPE gobridge_request_size_bytes_total counter\n")
+	for e := Endpoint(0); e < AllEndpoints; e++ {
+		fmt.Fprintf(&buf, "gobridge_request_size_bytes_total{endpoint=%q} %d\n", e.Path(), m.requestBytes[e])
+	}
+
+	buf.WriteString("# HELP gobridge_response_size_bytes_total Total size of response bodies per endpoint.\n")
+	buf.WriteString("# TYPE gobridge_response_size_bytes_total counter\n")
+	for e := Endpoint(0); e < AllEndpoints; e++ {
+		fmt.Fprintf(&buf, "gobridge_response_size_bytes_total{endpoint=%q} %d\n", e.Path(), m.responseBytes[e])
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = w.Write(buf.Bytes())
+}
+{{end}}`
@@ -0,0 +1,56 @@
+package templates
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// Overrides replace built in templates with those defined in the files of
+// Dir. Each output reads the file named after its root template, such as
+// server.tmpl or ts.tmpl, in which {{define "name"}} replaces the template of
+// that name, so a single part such as the TypeScript baseURL can be changed.
+type Overrides struct {
+	Dir string // Empty for the built in templates
+}
+
+func (o Overrides) apply(t *template.Template) (*template.Template, error) {
+	if o.Dir == "" {
+		return t, nil
+	}
+
+	path := filepath.Join(o.Dir, t.Name()+".tmpl")
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return t, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	// Parsing into a template of its own leaves the root intact, replacing
+	// only what the file defines.
+	_, err = t.New(path).Parse(string(b))
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// funcs returns the functions available to every template, along with extra.
+func funcs(extra template.FuncMap) template.FuncMap {
+	res := template.FuncMap{
+		"ToCamelCase": ToCamelCase,
+		"ToLower":     strings.ToLower,
+		"GoDoc":       GoDoc,
+		"JSDoc":       JSDoc,
+	}
+
+	for name, fn := range extra {
+		res[name] = fn
+	}
+
+	return res
+}
@@ -0,0 +1,193 @@
+package templates
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/template"
+)
+
+// Scaffold is the starting point of a new API package, created by gobridge init.
+type Scaffold struct {
+	Package string // Name of the API package
+	API     string // Name of the API interface
+	Import  string // Import path of the API package
+	Config  string // Path of the config file relative to the API package, used by go:generate
+}
+
+// scaffoldFiles are the templates of the files created, keyed by their path
+// relative to the API package. The paths are templates too.
+var scaffoldFiles = map[string]string{
+	"{{.Package}}.go":          scaffoldAPITemplate,
+	"types.go":                 scaffoldTypesTemplate,
+	"{{.Package}}_test.go":     scaffoldTestTemplate,
+	"cmd/{{.Package}}/main.go": scaffoldMainTemplate,
+}
+
+// Write creates the files of the scaffold in the API package directory,
+// returning their paths. Nothing is written if any of the files exist.
+func (s *Scaffold) Write(dir string) ([]string, error) {
+	files := make(map[string][]byte)
+	for name, tmpl := range scaffoldFiles {
+		var path bytes.Buffer
+		err := template.Must(template.New("").Parse(name)).Execute(&path, s)
+		if err != nil {
+			return nil, err
+		}
+
+		var src bytes.Buffer
+		err = executeGo(&src, template.Must(template.New("").Parse(tmpl)), s)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path.String(), err)
+		}
+
+		files[filepath.Join(dir, path.String())] = src.Bytes()
+	}
+
+	for path := range files {
+		if _, err := os.Stat(path); err == nil {
+			return nil, fmt.Errorf("%s already exists", path)
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+
+	var paths []string
+	for path, src := range files {
+		err := os.MkdirAll(filepath.Dir(path), os.ModePerm)
+		if err != nil {
+			return nil, err
+		}
+
+		err = os.WriteFile(path, src, 0o644)
+		if err != nil {
+			return nil, err
+		}
+
+		paths = append(paths, path)
+	}
+
+	return paths, nil
+}
+
+var scaffoldAPITemplate = `package {{.Package}}
+
+import "context"
+
+//go:generate go run github.com/luno/gobridge generate -config {{.Config}}
+
+// {{.API}} is served over HTTP by the generated server. Every method takes a
+// context and returns an error, and gets an endpoint named after it.
+type {{.API}} interface {
+	Hello(ctx context.Context, name string) (greeting Greeting, err error)
+}
+`
+
+var scaffoldTypesTemplate = `package {{.Package}}
+
+import "time"
+
+// Greeting is returned by {{.API}}.Hello.
+type Greeting struct {
+	Message string
+	At      time.Time
+}
+`
+
+var scaffoldMainTemplate = `package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"{{.Import}}"
+	"{{.Import}}/server"
+)
+
+var addr = flag.String("addr", ":8080", "Address to listen on")
+
+func main() {
+	flag.Parse()
+
+	// Endpoints can require more than the basic check, e.g. an admin role.
+	auth := server.AuthConfig{
+		server.HelloEndpoint: authenticate,
+	}
+
+	server.New(&{{.Package}}API{}, auth, authenticate, server.WithLogger(slog.Default()))
+
+	slog.Info("listening", "addr", *addr)
+	err := http.ListenAndServe(*addr, nil)
+	if err != nil {
+		slog.Error("server stopped", "error", err)
+		os.Exit(1)
+	}
+}
+
+// authenticate checks the Authorization header of every request.
+func authenticate(ctx context.Context, token string) (bool, error) {
+	// TODO: Verify the token.
+	if token == "" {
+		return false, errors.New("no authorization token")
+	}
+
+	return true, nil
+}
+
+// {{.Package}}API implements {{.Package}}.{{.API}}.
+type {{.Package}}API struct{}
+
+func (a *{{.Package}}API) Hello(ctx context.Context, name string) ({{.Package}}.Greeting, error) {
+	return {{.Package}}.Greeting{
+		Message: fmt.Sprintf("Hello, %s!", name),
+		At:      time.Now(),
+	}, nil
+}
+`
+
+var scaffoldTestTemplate = `package {{.Package}}_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"{{.Import}}"
+	"{{.Import}}/client"
+	"{{.Import}}/server"
+)
+
+type fake{{.API}} struct{}
+
+func (f *fake{{.API}}) Hello(ctx context.Context, name string) ({{.Package}}.Greeting, error) {
+	return {{.Package}}.Greeting{Message: "Hello, " + name}, nil
+}
+
+func TestHello(t *testing.T) {
+	allow := func(ctx context.Context, token string) (bool, error) { return true, nil }
+	server.New(&fake{{.API}}{}, nil, allow)
+
+	srv := httptest.NewServer(http.DefaultServeMux)
+	defer srv.Close()
+
+	cl := client.NewClient(srv.URL, client.WithAuthorization("token"))
+
+	g, err := cl.Hello(context.Background(), "gopher")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if g.Message != "Hello, gopher" {
+		t.Errorf("got message %q, want %q", g.Message, "Hello, gopher")
+	}
+}
+`
@@ -1,51 +1,150 @@
 package templates
 
 import (
+	"bytes"
+	"go/format"
+	"io"
 	"os"
+	"sort"
 	"strings"
 	"text/template"
-
-	"github.com/luno/gobridge/reader"
 )
 
+// HTTPServer is the data of the server template. The template is also passed
+// StdImports and Imports, the standard library and other packages imported.
 type HTTPServer struct {
-	API      string
-	Imports  []string
-	Paths    []Path
-	Handlers []HTTPHandler
+	API           string // Qualified API interface, e.g. "backend.Example"
+	Imports       []string
+	Paths         []Path
+	Handlers      []HTTPHandler
+	Int64AsString bool // Encode int64 and uint64 values as JSON strings
 }
 
+// Path is an endpoint of the server.
 type Path struct {
-	Camelcase string
-	Lowercase string
+	Camelcase string // Method name, e.g. "WhatsTheTime"
+	Lowercase string // URL path without the leading slash, e.g. "example/whatsthetime"
+	Timeout   string // Go expression of the declared timeout, empty when not set
+
+	Deprecation string // Value of the Deprecation header, empty when the endpoint isn't deprecated
+	Sunset      string // Value of the Sunset header, empty when not set
 }
 
+// Field is a field of a generated struct or TypeScript interface.
+type Field struct {
+	Name     string
+	Type     string // Go or TypeScript type expression
+	Doc      string
+	Optional bool   // Left out of the JSON encoding when empty
+	Arg      string // Parameter of the generated Go client method, which can differ from Name to avoid clashes
+}
+
+// SerialisationTypes are the fields of the request and response types of a handler.
 type SerialisationTypes struct {
-	Response []reader.TypeSignature
-	Request  []reader.TypeSignature
+	Response []Field
+	Request  []Field
 }
 
+// HTTPHandler is an API method served at URL, the data of the types and
+// handler server templates and the method client template.
 type HTTPHandler struct {
-	Method         string
-	API            string
-	URL            string
-	RequestType    string
-	Params         []string
-	Results        []string
+	Method         string   // Name of the API method
+	Doc            string   // Doc comment of the API method
+	Passthrough    bool     // The single parameter and result are the request and response bodies
+	API            string   // Qualified API interface
+	URL            string   // URL path without the leading slash
+	RequestType    string   // Prefix of the request and response type names
+	Params         []string // Names of the parameters, after the context
 	ResponseType   string
-	ResponseParams []string
+	ResponseParams []string // Names of the results, before the error
 	Types          SerialisationTypes
 }
 
-func (s *HTTPServer) AddTo(file *os.File) error {
-	funcMap := template.FuncMap{
-		"ToCamelCase": func(s string) string {
-			ls := strings.Split(s, "")
-			ls[0] = strings.ToUpper(ls[0])
-			return strings.Join(ls, "")
-		},
+// serverStdImports are imported by every generated server regardless of the
+// types used by the API.
+var serverStdImports = []string{
+	"bytes",
+	"context",
+	"encoding",
+	"encoding/json",
+	"errors",
+	"fmt",
+	"io",
+	"io/ioutil",
+	"log/slog",
+	"net/http",
+	"reflect",
+	"runtime/debug",
+	"sort",
+	"strconv",
+	"strings",
+	"sync",
+	"time",
+}
+
+// importGroups merges the default imports with the API imports, removing
+// duplicates and splitting them into standard library and module imports.
+func importGroups(defaults []string, imports []string) (std []string, other []string) {
+	seen := make(map[string]bool)
+	for _, imp := range append(append([]string(nil), defaults...), imports...) {
+		if imp == "" || seen[imp] {
+			continue
+		}
+		seen[imp] = true
+
+		if strings.Contains(strings.Split(imp, "/")[0], ".") {
+			other = append(other, imp)
+		} else {
+			std = append(std, imp)
+		}
+	}
+
+	sort.Strings(std)
+	sort.Strings(other)
+	return std, other
+}
+
+// executeGo executes a Go source template, writing the output gofmt'd. The
+// unformatted output is written when it has syntax errors so they can be inspected.
+func executeGo(w io.Writer, t *template.Template, data interface{}) error {
+	var buf bytes.Buffer
+	err := t.Execute(&buf, data)
+	if err != nil {
+		return err
+	}
+
+	src, fmtErr := format.Source(buf.Bytes())
+	if fmtErr != nil {
+		src = buf.Bytes()
+	}
+
+	_, err = w.Write(src)
+	if err != nil {
+		return err
+	}
+
+	return fmtErr
+}
+
+func (s *HTTPServer) AddTo(file *os.File, o Overrides) error {
+	std, other := importGroups(serverStdImports, s.Imports)
+	data := struct {
+		*HTTPServer
+		StdImports []string
+		Imports    []string
+	}{
+		HTTPServer: s,
+		StdImports: std,
+		Imports:    other,
 	}
-	return template.Must(template.New("").Funcs(funcMap).Parse(serverTemplate)).Execute(file, s)
+
+	t := template.Must(template.New("server").Funcs(funcs(nil)).Parse(serverTemplate + handlerTemplates + metricsTemplate + tracingTemplate + decodingTemplate + encodingTemplate))
+	t, err := o.apply(t)
+	if err != nil {
+		return err
+	}
+
+	return executeGo(file, t, data)
 }
 
 var serverTemplate = `// Code generated by gobridge; DO NOT EDIT.
@@ -53,21 +152,31 @@ var serverTemplate = `// Code generated by gobridge; DO NOT EDIT.
 package server
 
 import (
-	"context"
-	"encoding/json"
-	"io/ioutil"
-	"net/http"
-	"strings"
-{{range $key, $value := .Imports }}
+{{- range $key, $value := .StdImports }}
+	"{{$value}}"
+{{- end }}
+{{ range $key, $value := .Imports }}
 	"{{$value}}"
 {{- end }}
 )
 
-func New(api {{.API}}, a AuthConfig, basicAuth func(ctx context.Context, token string) (bool, error)) *Server {
+func New(api {{.API}}, a AuthConfig, basicAuth func(ctx context.Context, token string) (bool, error), opts ...Option) *Server {
 	s := &Server{
 		AdditionalAuth: a,
 		Basic:          basicAuth,
 		API:            api,
+		Logger:         slog.Default(),
+		Tracer:         W3CTracer{},
+		MaxBodySize:    DefaultMaxBodySize,
+		Timeouts:       make(map[Endpoint]time.Duration),
+	}
+
+	for e, d := range declaredTimeouts {
+		s.Timeouts[e] = d
+	}
+
+	for _, o := range opts {
+		o(s)
 	}
 
 	s.registerHandlers()
@@ -75,12 +184,51 @@ func New(api {{.API}}, a AuthConfig, basicAuth func(ctx context.Context, token s
 	return s
 }
 
+// Option configures optional behaviour of the Server.
+type Option func(s *Server)
+
+// WithLogger sets the logger used to emit one structured record per request.
+// Passing nil disables request logging.
+func WithLogger(l *slog.Logger) Option {
+	return func(s *Server) {
+		s.Logger = l
+	}
+}
+
+// DefaultMaxBodySize is the default limit on the size of request bodies.
+const DefaultMaxBodySize = 10 << 20
+
+// WithMaxBodySize limits the size of request bodies, responding with 413 when
+// exceeded. A size of zero or less removes the limit.
+func WithMaxBodySize(n int64) Option {
+	return func(s *Server) {
+		s.MaxBodySize = n
+	}
+}
+
+// WithTimeout sets the deadline of the context passed to the API method of an
+// endpoint, overriding any declared with //gobridge:timeout. AllEndpoints sets
+// the deadline for endpoints without their own. Requests that exceed their
+// deadline get a 504 response once the API method returns, whatever it returned.
+func WithTimeout(e Endpoint, d time.Duration) Option {
+	return func(s *Server) {
+		s.Timeouts[e] = d
+	}
+}
+
 type AuthConfig map[Endpoint]func(ctx context.Context, token string) (bool, error)
 
 type Server struct {
 	AdditionalAuth AuthConfig
 	Basic          func(ctx context.Context, token string) (bool, error)
 	API            {{.API}}
+	Logger         *slog.Logger
+	Observers      []Observer
+	Metrics        *Metrics
+	Tracer         Tracer
+	MaxBodySize    int64
+	Timeouts       map[Endpoint]time.Duration
+	DecodeMode     DecodeMode
 }
 
 type Endpoint int
@@ -92,6 +240,30 @@ var (
 	AllEndpoints          Endpoint = {{(len .Paths)}}
 )
 
+// declaredTimeouts are the deadlines declared with //gobridge:timeout on the API methods.
+var declaredTimeouts = map[Endpoint]time.Duration{
+{{- range $key, $value := .Paths }}
+{{- if $value.Timeout }}
+	{{$value.Camelcase}}Endpoint: {{$value.Timeout}},
+{{- end }}
+{{- end }}
+}
+
+// deprecation is the Deprecation and Sunset headers sent by a deprecated endpoint.
+type deprecation struct {
+	Deprecation string
+	Sunset      string
+}
+
+// deprecations are the endpoints of the methods documented as deprecated.
+var deprecations = map[Endpoint]deprecation{
+{{- range $key, $value := .Paths }}
+{{- if $value.Deprecation }}
+	{{$value.Camelcase}}Endpoint: {Deprecation: "{{$value.Deprecation}}", Sunset: "{{$value.Sunset}}"},
+{{- end }}
+{{- end }}
+}
+
 func (ep Endpoint) Path() string {
 	switch ep {
 	case AllEndpoints:
@@ -109,18 +281,84 @@ func (s *Server) registerHandlers() {
 {{- range $key, $value := .Handlers }}
 	http.HandleFunc("/{{$value.URL}}", s.Wrap({{$value.Method}}Endpoint, Handle{{$value.Method}}(s.API)))
 {{- end }}
+
+	if s.Metrics != nil {
+		http.Handle("/metrics", s.Metrics)
+	}
 }
 
 func (s *Server) Wrap(e Endpoint, fn func(w http.ResponseWriter, r *http.Request)) func(w http.ResponseWriter, r *http.Request) {
-	return func(w http.ResponseWriter, r *http.Request) {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		w := &responseWriter{ResponseWriter: rw, status: http.StatusOK}
+		body := &countingReader{ReadCloser: r.Body}
+		if r.Body != nil {
+			r.Body = body
+		}
+
+		s.requestStarted(r.Context(), e)
+		defer func() {
+			p := recover()
+			if p != nil && p != http.ErrAbortHandler {
+				if w.wroteHeader {
+					// Too late to change the response, but make sure it is logged as a failure.
+					w.status = http.StatusInternalServerError
+				} else {
+					// The panic value can hold anything, so it's only logged.
+					http.Error(w, "internal server error", http.StatusInternalServerError)
+				}
+				w.err = fmt.Errorf("panic: %v", p)
+				w.stack = debug.Stack()
+			}
+
+			latency := time.Since(start)
+			s.logRequest(r, e, w, latency)
+
+			info := RequestInfo{
+				Status:        w.status,
+				Duration:      latency,
+				RequestBytes:  body.n,
+				ResponseBytes: w.bytes,
+			}
+			if w.status >= http.StatusBadRequest {
+				info.ErrorCode = http.StatusText(w.status)
+			}
+			s.requestFinished(r.Context(), e, info)
+
+			// http.ErrAbortHandler aborts the response, which the http.Server
+			// does when it recovers it.
+			if p == http.ErrAbortHandler {
+				panic(p)
+			}
+		}()
+
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Kind, Authorization")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Kind, Authorization, traceparent, tracestate")
 
 		if r.Method == http.MethodOptions {
 			w.WriteHeader(http.StatusOK)
 			return
 		}
 
+		if d, ok := deprecations[e]; ok {
+			w.Header().Set("Access-Control-Expose-Headers", "Deprecation, Sunset")
+			w.Header().Set("Deprecation", d.Deprecation)
+			if d.Sunset != "" {
+				w.Header().Set("Sunset", d.Sunset)
+			}
+			s.warnDeprecated(r, e, d)
+		}
+
+		if s.Tracer != nil {
+			r = r.WithContext(s.Tracer.Extract(r.Context(), TraceContextFromHeader(r.Header)))
+		}
+
+		if s.MaxBodySize > 0 && r.Body != nil {
+			r.Body = http.MaxBytesReader(w, r.Body, s.MaxBodySize)
+		}
+
+		r = r.WithContext(context.WithValue(r.Context(), decodeModeKey{}, s.DecodeMode))
+
 		allow, msg, reason := checkAuth(w, r, s.Basic)
 		if !allow {
 			http.Error(w, msg, reason)
@@ -148,10 +386,147 @@ func (s *Server) Wrap(e Endpoint, fn func(w http.ResponseWriter, r *http.Request
 			}
 		}
 
+		// The deadline starts once the request is authorised, so slow auth
+		// checks don't use up the time of the API call.
+		if d, ok := s.timeout(e); ok {
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+			r = r.WithContext(ctx)
+		}
+
 		fn(w, r)
 	}
 }
 
+// timeout returns the deadline of the endpoint, falling back to the one set
+// for AllEndpoints.
+func (s *Server) timeout(e Endpoint) (time.Duration, bool) {
+	if d, ok := s.Timeouts[e]; ok && d > 0 {
+		return d, true
+	}
+
+	d, ok := s.Timeouts[AllEndpoints]
+	return d, ok && d > 0
+}
+
+// warnDeprecated logs a call to a deprecated endpoint, so that the consumers
+// still calling it can be found before it's removed.
+func (s *Server) warnDeprecated(r *http.Request, e Endpoint, d deprecation) {
+	if s.Logger == nil {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("endpoint", e.Path()),
+		slog.String("user_agent", r.UserAgent()),
+	}
+	if d.Sunset != "" {
+		attrs = append(attrs, slog.String("sunset", d.Sunset))
+	}
+
+	s.Logger.LogAttrs(r.Context(), slog.LevelWarn, "gobridge deprecated endpoint called", attrs...)
+}
+
+func (s *Server) logRequest(r *http.Request, e Endpoint, w *responseWriter, latency time.Duration) {
+	if s.Logger == nil {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("endpoint", e.Path()),
+		slog.Int("status", w.status),
+		slog.Duration("latency", latency),
+	}
+
+	level := slog.LevelInfo
+	if w.status >= http.StatusBadRequest {
+		level = slog.LevelWarn
+	}
+	if w.status >= http.StatusInternalServerError {
+		level = slog.LevelError
+	}
+
+	if w.err != nil {
+		attrs = append(attrs, slog.String("error", w.err.Error()))
+	}
+	if w.stack != nil {
+		attrs = append(attrs, slog.String("stack", string(w.stack)))
+	}
+
+	s.Logger.LogAttrs(r.Context(), level, "gobridge request", attrs...)
+}
+
+// responseWriter records the status code, size and error of a response so
+// that it can be logged and observed once the request has completed.
+type responseWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	bytes       int64
+	err         error
+	stack       []byte // Stack trace of a recovered panic
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+
+	w.status = status
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// writeError responds with the status and error message, recording the error
+// for the request log when the writer was created by Server.Wrap. A
+// DecodeError is written as JSON.
+func writeError(w http.ResponseWriter, status int, err error) {
+	if rw, ok := w.(*responseWriter); ok {
+		rw.err = err
+	}
+
+	var decodeErr *DecodeError
+	if errors.As(err, &decodeErr) {
+		b, _ := json.Marshal(decodeErr)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = w.Write(b)
+		return
+	}
+
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(err.Error()))
+}
+
+// bodyErrorStatus returns the status for a failure to read the request body.
+func bodyErrorStatus(err error) int {
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		return http.StatusRequestEntityTooLarge
+	}
+
+	return http.StatusBadRequest
+}
+
+// apiErrorStatus returns the status for an error returned by an API method.
+func apiErrorStatus(err error) int {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return http.StatusGatewayTimeout
+	}
+
+	return http.StatusInternalServerError
+}
+
 func checkAuth(w http.ResponseWriter, r *http.Request, authFunc func(ctx context.Context, token string) (bool, error)) (bool, string, int) {
 	t := strings.TrimSpace(r.Header.Get("Authorization"))
 	allow, err := authFunc(r.Context(), t)
@@ -166,63 +541,78 @@ func checkAuth(w http.ResponseWriter, r *http.Request, authFunc func(ctx context
 
 	return true, "", http.StatusOK
 }
-{{ range $key, $value := .Handlers }}
-type {{$value.RequestType}}Request struct {
-{{- range $key, $value := $value.Types.Request }}
-{{- if eq $value.Type 1}}
-	{{$value.Name | ToCamelCase}} {{if ne $value.GoPackage ""}}{{$value.GoPackage}}.{{end}}{{$value.Kind}}
-{{- end}}
-{{- if eq $value.Type 2}}
-	{{$value.Name  | ToCamelCase}} []{{if ne $value.GoPackage ""}}{{$value.GoPackage}}.{{end}}{{$value.Kind}}
-{{- end}}
+{{ template "metrics" . }}
+{{- template "tracing" . }}
+{{- template "decoding" . }}
+{{- template "encoding" . }}{{ range .Handlers }}
+{{ if not .Passthrough }}{{ template "types" . }}{{ end }}
+{{ template "handler" . }}
+{{ end }}
+`
+
+// handlerTemplates are executed for each HTTPHandler of the server.
+var handlerTemplates = `{{define "types"}}
+// {{.RequestType}}Request is the request body of {{.Method}}.
+type {{.RequestType}}Request struct {
+{{- range .Types.Request }}
+	{{.Name | ToCamelCase}} {{.Type}}
 {{- end }}
 }
 
-type {{$value.RequestType}}Response struct {
-{{- range $key, $value := $value.Types.Response }}
-{{- if eq $value.Type 1}}
-	{{$value.Name | ToCamelCase}} {{if ne $value.GoPackage ""}}{{$value.GoPackage}}.{{end}}{{$value.Kind}}
-{{- end}}
-{{- if eq $value.Type 2}}
-	{{$value.Name | ToCamelCase}} []{{if ne $value.GoPackage ""}}{{$value.GoPackage}}.{{end}}{{$value.Kind}}
-{{- end}}
+// {{.RequestType}}Response is the response body of {{.Method}}.
+type {{.RequestType}}Response struct {
+{{- range .Types.Response }}
+	{{.Name | ToCamelCase}} {{.Type}}
 {{- end }}
 }
+{{end}}
 
-func Handle{{$value.Method}}(api {{.API}}) func(http.ResponseWriter, *http.Request) {
+{{define "handler"}}
+func Handle{{.Method}}(impl {{.API}}) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		b, err := ioutil.ReadAll(r.Body)
 		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			_, _ = w.Write([]byte(err.Error()))
+			writeError(w, bodyErrorStatus(err), err)
 			return
 		}
 
-		var req {{$value.RequestType}}Request
-		err = json.Unmarshal(b, &req)
+		var req {{if .Passthrough}}{{(index .Types.Request 0).Type}}{{else}}{{.RequestType}}Request{{end}}
+		err = decodeRequest(r.Context(), b, &req)
 		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			_, _ = w.Write([]byte(err.Error()))
+			writeError(w, http.StatusBadRequest, err)
 			return
 		}
 
 		t := strings.TrimSpace(r.Header.Get("Authorization"))
 		ctx := context.WithValue(r.Context(), "authorization_header", t)
-
-		{{ range $key2, $value2 := $value.Results }}{{if $key2}}, {{end}}{{ $value2 }}{{ end }}{{ if eq (len $value.Results) 1 }} = {{ end }}{{ if not (eq (len $value.Results) 1) }} := {{ end }}api.{{$value.Method}}(ctx{{range $key3, $value3 := .Params }}, req.{{ $value3  | ToCamelCase }}{{end }})
+{{ if .Passthrough }}
+		resp, err := impl.{{.Method}}(ctx, req)
+		if ctx.Err() == context.DeadlineExceeded {
+			// The client has given up on a late response, even a successful one.
+			writeError(w, http.StatusGatewayTimeout, ctx.Err())
+			return
+		}
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			_, _ = w.Write([]byte(err.Error()))
+			writeError(w, apiErrorStatus(err), err)
 			return
 		}
+{{ else }}
+		var resp {{.ResponseType}}Response
+		{{ range .ResponseParams }}resp.{{ . | ToCamelCase }}, {{ end }}err = impl.{{.Method}}(ctx{{ range .Params }}, req.{{ . | ToCamelCase }}{{ end }})
+		if ctx.Err() == context.DeadlineExceeded {
+			// The client has given up on a late response, even a successful one.
+			writeError(w, http.StatusGatewayTimeout, ctx.Err())
+			return
+		}
+		if err != nil {
+			writeError(w, apiErrorStatus(err), err)
+			return
+		}
+{{ end }}
 
-		var resp {{$value.ResponseType}}Response
-		{{range $key2, $value2 := $value.ResponseParams }}{{if $key2}}, {{end}}{{if eq $value2 "_"}}{{else if $value2}}resp.{{end}}{{$value2  | ToCamelCase }}{{end}} = {{ range $key3, $value3 := .Results }}{{if $key3}}, {{end}}{{ $value3 }}{{ end }}
-
-		respBody, err := json.Marshal(resp)
+		respBody, err := EncodeJSON(resp)
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			_, _ = w.Write([]byte(err.Error()))
+			writeError(w, http.StatusInternalServerError, err)
 			return
 		}
 
@@ -234,5 +624,4 @@ func Handle{{$value.Method}}(api {{.API}}) func(http.ResponseWriter, *http.Reque
 		}
 	}
 }
-{{ end }}
-`
+{{end}}`
@@ -0,0 +1,122 @@
+package templates
+
+// tracingTemplate is rendered into the generated server and provides W3C
+// trace context propagation through a pluggable Tracer.
+var tracingTemplate = `{{define "tracing"}}
+// TraceContext holds the W3C trace context propagated with a request.
+type TraceContext struct {
+	TraceParent string
+	TraceState  string
+}
+
+// TraceContextFromHeader reads the traceparent and tracestate headers. An
+// invalid traceparent is discarded together with the tracestate.
+func TraceContextFromHeader(h http.Header) TraceContext {
+	tc := TraceContext{
+		TraceParent: strings.TrimSpace(h.Get("traceparent")),
+		TraceState:  strings.TrimSpace(h.Get("tracestate")),
+	}
+
+	if !validTraceParent(tc.TraceParent) {
+		return TraceContext{}
+	}
+
+	return tc
+}
+
+// SetHeader writes the trace context to the traceparent and tracestate headers.
+func (tc TraceContext) SetHeader(h http.Header) {
+	if tc.TraceParent == "" {
+		return
+	}
+
+	h.Set("traceparent", tc.TraceParent)
+	if tc.TraceState != "" {
+		h.Set("tracestate", tc.TraceState)
+	}
+}
+
+// Tracer bridges the generated server and client to a tracing library.
+type Tracer interface {
+	// Extract returns a context carrying the trace context received with an
+	// incoming request. It is called before the API method.
+	Extract(ctx context.Context, tc TraceContext) context.Context
+
+	// Inject returns the trace context of ctx to send with an outgoing request.
+	Inject(ctx context.Context) TraceContext
+}
+
+// WithTracer sets the Tracer used to extract the trace context of incoming requests.
+func WithTracer(t Tracer) Option {
+	return func(s *Server) {
+		s.Tracer = t
+	}
+}
+
+// W3CTracer is the default Tracer. It stores the incoming trace context in
+// the request context unchanged so that it flows through to outgoing client
+// calls without requiring a tracing library.
+type W3CTracer struct{}
+
+func (W3CTracer) Extract(ctx context.Context, tc TraceContext) context.Context {
+	if tc.TraceParent == "" {
+		return ctx
+	}
+
+	return ContextWithTraceContext(ctx, tc)
+}
+
+func (W3CTracer) Inject(ctx context.Context) TraceContext {
+	return TraceContextFromContext(ctx)
+}
+
+type traceContextKey struct{}
+
+// ContextWithTraceContext returns a copy of ctx carrying tc.
+func ContextWithTraceContext(ctx context.Context, tc TraceContext) context.Context {
+	return context.WithValue(ctx, traceContextKey{}, tc)
+}
+
+// TraceContextFromContext returns the trace context stored in ctx, if any.
+func TraceContextFromContext(ctx context.Context) TraceContext {
+	tc, _ := ctx.Value(traceContextKey{}).(TraceContext)
+	return tc
+}
+
+// validTraceParent checks the version-traceid-parentid-flags format of a
+// traceparent header, e.g. 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+func validTraceParent(tp string) bool {
+	parts := strings.Split(tp, "-")
+	if len(parts) < 4 {
+		return false
+	}
+
+	// Future versions may append fields, version 00 may not
+	if parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return false
+	}
+
+	for i, l := range []int{2, 32, 16, 2} {
+		if len(parts[i]) != l {
+			return false
+		}
+
+		allZero := true
+		for _, c := range parts[i] {
+			if !(c >= '0' && c <= '9') && !(c >= 'a' && c <= 'f') {
+				return false
+			}
+			if c != '0' {
+				allZero = false
+			}
+		}
+
+		// Trace and parent IDs of all zeros are invalid
+		if allZero && (i == 1 || i == 2) {
+			return false
+		}
+	}
+
+	return true
+}
+{{end}}`
@@ -4,97 +4,241 @@ import (
 	"os"
 	"strings"
 	"text/template"
-
-	"github.com/luno/gobridge/reader"
 )
 
+// TSService is the data of the ts template.
 type TSService struct {
-	Name        string
-	Interfaces  []TSInterface
-	Enums       []TSEnum
-	ModName     string
-	MethodNames []string
+	Name       string   // Name of the Angular service class
+	Doc        string   // Doc comment of the API interface
+	Imports    []string // Import statements required by mapped types
+	Interfaces []TSInterface
+	Enums      []TSEnum
+	ModName    string
+	Methods    []TSMethod
 }
 
+// TSMethod is a method of the service, the data of the method and baseURL templates.
+type TSMethod struct {
+	Name             string
+	Doc              string
+	Service          string // Name of the service
+	Path             string // URL path of the endpoint, as served by the generated server
+	Request          string // TypeScript type of the request body
+	Response         string // TypeScript type of the response body
+	SerializeRequest bool   // The request has fields that need converting before sending
+	ReviveResponse   bool   // The response has fields that need converting after receiving
+}
+
+// TSInterface is a TypeScript interface for a struct or a request or response,
+// the data of the interface template.
 type TSInterface struct {
-	Name   string
-	Fields []reader.TypeSignature
+	Name        string
+	Doc         string
+	Fields      []Field
+	Conversions []TSConversion
+}
+
+// TSConversion converts a field between its JSON and TypeScript representation.
+type TSConversion struct {
+	Field     string // Property accessor, such as .Name or ["-"]
+	Revive    string // Expression converting the JSON value of v.Field
+	Serialize string // Expression converting the TypeScript value of v.Field
 }
 
+// TSEnum is a TypeScript enum, the data of the enum template.
 type TSEnum struct {
 	Name   string
-	Fields map[string]string
+	Doc    string
+	Fields map[string]string // Values by constant name
+	Docs   map[string]string // Doc comments by constant name
 }
 
-func (tss *TSService) AddTo(file *os.File) error {
-	funcMap := template.FuncMap{
-		"ToLower": strings.ToLower,
-		"ToCamelCase": func(s string) string {
-			ls := strings.Split(s, "")
-			ls[0] = strings.ToUpper(ls[0])
-			return strings.Join(ls, "")
-		},
+func (tss *TSService) AddTo(file *os.File, o Overrides) error {
+	t := template.Must(template.New("ts").Funcs(funcs(nil)).Parse(tsServiceTemplate))
+	// Parsed separately so the text between definitions isn't output
+	template.Must(t.New("parts").Parse(tsPartsTemplate))
+
+	t, err := o.apply(t)
+	if err != nil {
+		return err
 	}
 
-	return template.Must(template.New("").Funcs(funcMap).Parse(tsServiceTemplate)).Execute(file, tss)
+	return t.Execute(file, tss)
 }
 
-var tsServiceTemplate = `import { Injectable } from '@angular/core';
-import { HttpClient } from '@angular/common/http';
-import { environment } from '../../environments/environment';
+// ToCamelCase upper cases the first letter of s, giving the exported Go
+// field name used in the JSON encoding.
+func ToCamelCase(s string) string {
+	if s == "" {
+		return s
+	}
+
+	ls := strings.Split(s, "")
+	ls[0] = strings.ToUpper(ls[0])
+	return strings.Join(ls, "")
+}
 
-@Injectable({
+var tsServiceTemplate = `{{ template "imports" . }}
+
+// HeaderProvider returns additional headers to send with every request, such as
+// the W3C traceparent and tracestate headers of the active span.
+export type HeaderProvider = () => { [name: string]: string };
+
+export const {{.Name}}HeaderProvider = new InjectionToken<HeaderProvider>('{{.Name}}HeaderProvider');
+
+{{ JSDoc "" .Doc }}@Injectable({
   providedIn: 'root'
 })
 export class {{.Name}} {
 
-  constructor(private http: HttpClient) {}
-  {{- range $key, $value := .MethodNames }}
+  constructor(private http: HttpClient, @Optional() @Inject({{.Name}}HeaderProvider) private headerProvider?: HeaderProvider) {}
 
-  // @ts-ignore
-  public async {{$value}}(payload: {{$value}}Request): Promise<{{$value}}Response> {
-    // tslint:disable-next-line:max-line-length
-    return await this.http.post(environment.BackendURL + '/{{$.Name | ToLower}}/{{$value | ToLower}}', JSON.stringify(payload)).toPromise() as {{$value}}Response;
+  private headers(): HttpHeaders {
+    return new HttpHeaders(this.headerProvider ? this.headerProvider() : {});
   }
-
+  {{- range .Methods }}
+{{ template "method" . }}
 {{- end }}
 }
-{{- range $key, $value := .Interfaces }}
-{{- if eq (len $value.Fields) 0 }}
 
-// tslint:disable-next-line:no-empty-interface
-export interface {{$value.Name}} {}
-{{- end}}
-{{- if ge (len $value.Fields) 1 }}
+function mapArray(v: any, fn: (e: any) => any): any {
+  return v == null ? v : v.map(fn);
+}
+
+function mapRecord(v: any, fn: (e: any) => any): any {
+  if (v == null) {
+    return v;
+  }
+
+  const r: any = {};
+  for (const k of Object.keys(v)) {
+    r[k] = fn(v[k]);
+  }
+  return r;
+}
 
-export interface {{$value.Name}} {
-{{- range $key2, $value2 := $value.Fields }}
-{{- if eq $value2.Type 1}}
-  {{ $value2.Name | ToCamelCase}}: {{ $value2.Kind }};
+// Go encodes time.Time as an RFC3339 string.
+export function reviveDate(v: any): Date {
+  return typeof v === 'string' ? new Date(v) : v;
+}
+
+export function serializeDate(v: any): any {
+  return v instanceof Date ? v.toISOString() : v;
+}
+
+const durationUnits: { [unit: string]: number } = {
+  ns: 1e-6, us: 1e-3, µs: 1e-3, ms: 1, s: 1e3, m: 6e4, h: 3.6e6,
+};
+
+// Go encodes time.Duration as nanoseconds, or as a string such as "1h2m3s"
+// when using a custom encoding. Durations are represented as milliseconds.
+export function reviveDuration(v: any): number {
+  if (typeof v === 'number') {
+    return v / 1e6;
+  }
+
+  if (typeof v !== 'string') {
+    return v;
+  }
+
+  const sign = v.startsWith('-') ? -1 : 1;
+  let ms = 0;
+  const re = /([0-9.]+)(ns|us|µs|ms|s|m|h)/g;
+  let match = re.exec(v);
+  while (match !== null) {
+    ms += parseFloat(match[1]) * durationUnits[match[2]];
+    match = re.exec(v);
+  }
+  return sign * ms;
+}
+
+export function serializeDuration(v: any): any {
+  return typeof v === 'number' ? Math.round(v * 1e6) : v;
+}
+{{- range .Interfaces }}
+{{- template "interface" . }}
+{{- end }}
+
+{{- range .Enums }}
+{{- template "enum" . }}
+{{- end }}
+`
+
+// tsPartsTemplate holds the templates executed for each part of the service,
+// which can be overridden individually.
+var tsPartsTemplate = `{{define "imports" -}}
+import { Inject, Injectable, InjectionToken, Optional } from '@angular/core';
+import { HttpClient, HttpHeaders } from '@angular/common/http';
+import { environment } from '../../environments/environment';
+{{- range .Imports }}
+{{.}}
+{{- end }}
 {{- end}}
-{{- if eq $value2.Type 0}}
-  {{ $value2.Name | ToCamelCase}}: {{ $value2.Kind }};
+
+{{define "baseURL"}}environment.BackendURL{{end}}
+
+{{define "method"}}
+{{ JSDoc "  " .Doc }}  // @ts-ignore
+  public async {{.Name}}(payload: {{.Request}}): Promise<{{.Response}}> {
+    // tslint:disable-next-line:max-line-length
+    const resp = await this.http.post({{ template "baseURL" . }} + '{{.Path}}', JSON.stringify({{if .SerializeRequest}}serialize{{.Request}}(payload){{else}}payload{{end}}), {headers: this.headers()}).toPromise();
+    return {{if .ReviveResponse}}revive{{.Response}}(resp){{else}}resp as {{.Response}}{{end}};
+  }
 {{- end}}
-{{- if eq $value2.Type 2}}
-  {{ $value2.Name | ToCamelCase}}: {{ $value2.Kind }}[];
+
+{{define "interface"}}
+{{- if eq (len .Fields) 0 }}
+
+{{ JSDoc "" .Doc }}// tslint:disable-next-line:no-empty-interface
+export interface {{.Name}} {}
 {{- end}}
+{{- if ge (len .Fields) 1 }}
+
+{{ JSDoc "" .Doc }}export interface {{.Name}} {
+{{- range .Fields }}
+{{ JSDoc "  " .Doc }}  {{ .Name }}{{ if .Optional }}?{{ end }}: {{ .Type }};
 {{- end }}
 }
 {{- end}}
+{{- if .Conversions }}
+
+export function revive{{.Name}}(v: any): {{.Name}} {
+  if (v == null) {
+    return v;
+  }
+
+  const r = { ...v };
+{{- range .Conversions }}
+  r{{.Field}} = {{.Revive}};
+{{- end }}
+  return r;
+}
+
+export function serialize{{.Name}}(v: {{.Name}}): any {
+  if (v == null) {
+    return v;
+  }
+
+  const r: any = { ...v };
+{{- range .Conversions }}
+  r{{.Field}} = {{.Serialize}};
 {{- end }}
+  return r;
+}
+{{- end}}
+{{- end}}
 
-{{- range $key, $value := .Enums }}
-{{- if eq (len $value.Fields) 0 }}
+{{define "enum"}}
+{{- if eq (len .Fields) 0 }}
 
-export enum {{$value.Name}} {}
+{{ JSDoc "" .Doc }}export enum {{.Name}} {}
 {{- end}}
-{{- if ge (len $value.Fields) 1 }}
+{{- if ge (len .Fields) 1 }}
 
-export enum {{$value.Name}} {
-{{- range $key2, $value2 := $value.Fields }}
-  {{ $key2 }} = {{ $value2 }},
+{{ JSDoc "" .Doc }}export enum {{.Name}} {
+{{- range $name, $value := .Fields }}
+{{ JSDoc "  " (index $.Docs $name) }}  {{ $name }} = {{ $value }},
 {{- end }}
 }
 {{- end}}
-{{- end }}
-`
+{{- end}}`
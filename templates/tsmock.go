@@ -0,0 +1,59 @@
+package templates
+
+import (
+	"os"
+	"text/template"
+)
+
+// TSMock is the data of the tsmock template, a mock of the TypeScript service
+// for frontend tests.
+type TSMock struct {
+	Name    string   // Name of the mocked service class
+	Module  string   // Module of the service, relative to the mock
+	Types   []string // Request and response types imported from the service module
+	Methods []TSMethod
+}
+
+func (m *TSMock) AddTo(file *os.File, o Overrides) error {
+	t := template.Must(template.New("tsmock").Funcs(funcs(nil)).Parse(tsMockTemplate))
+	template.Must(t.New("parts").Parse(tsMockMethodTemplate))
+
+	t, err := o.apply(t)
+	if err != nil {
+		return err
+	}
+
+	return t.Execute(file, m)
+}
+
+var tsMockTemplate = `import {
+{{- range $i, $t := .Types }}{{ if $i }},{{ end }} {{ $t }}{{ end }} } from '{{.Module}}';
+
+/**
+ * {{.Name}}Mock is a mock of {{.Name}} for tests which don't need a backend,
+ * provided in its place with {provide: {{.Name}}, useValue: new {{.Name}}Mock()}.
+ * Each method records its payload in Calls, and resolves with the result of
+ * its Handler when set, or its Returns response, which defaults to empty.
+ */
+export class {{.Name}}Mock {
+{{- range $i, $m := .Methods }}
+{{- if $i }}
+{{ end }}
+{{ template "method" $m }}
+{{- end }}
+}
+`
+
+// tsMockMethodTemplate is executed for each method of the mock.
+var tsMockMethodTemplate = `{{define "method"}}  public {{.Name}}Calls: {{.Request}}[] = [];
+  public {{.Name}}Handler?: (payload: {{.Request}}) => {{.Response}} | Promise<{{.Response}}>;
+  public {{.Name}}Returns?: {{.Response}};
+
+{{ JSDoc "  " .Doc }}  public async {{.Name}}(payload: {{.Request}}): Promise<{{.Response}}> {
+    this.{{.Name}}Calls.push(payload);
+    if (this.{{.Name}}Handler) {
+      return this.{{.Name}}Handler(payload);
+    }
+    return this.{{.Name}}Returns || ({} as {{.Response}});
+  }
+{{- end}}`
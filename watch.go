@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/luno/gobridge/config"
+)
+
+// watchedAPI is an API regenerated when the packages it was read from change.
+type watchedAPI struct {
+	api   config.API
+	dirs  []string // Directories of the API package and the module packages it imports
+	files map[string]fileState
+}
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// watchAPIs polls the API packages for changes until interrupted,
+// regenerating the outputs of the APIs which changed. Errors are reported
+// without stopping, so they can be fixed while it runs. The config file is
+// reloaded when it changes unless the API was set with flags, and applyFlags
+// applies the command line flags to each reloaded config.
+func watchAPIs(c *config.Config, single bool, interval time.Duration, applyFlags func(*config.Config)) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	apis := watchAll(c)
+	cfg := statFile(c.Path)
+
+	fmt.Fprintf(os.Stderr, "watching %d APIs for changes, press Ctrl-C to stop\n", len(apis))
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+		}
+
+		if !single && c.Path != "" && statFile(c.Path) != cfg {
+			cfg = statFile(c.Path)
+
+			next, err := config.Load(c.Path)
+			if err != nil {
+				report(err)
+				continue
+			}
+
+			applyFlags(next)
+			c = next
+			apis = watchAll(c)
+			continue
+		}
+
+		for _, w := range apis {
+			if !w.changed() {
+				continue
+			}
+
+			w.regenerate(c)
+		}
+	}
+}
+
+// watchAll generates the outputs of all the APIs, returning them to watch.
+func watchAll(c *config.Config) []*watchedAPI {
+	var res []*watchedAPI
+	for _, a := range c.APIs {
+		w := &watchedAPI{api: a, dirs: []string{a.Package}}
+		w.regenerate(c)
+		res = append(res, w)
+	}
+
+	return res
+}
+
+// changed reports whether any of the source files in the watched
+// directories were added, removed or modified since the last check.
+func (w *watchedAPI) changed() bool {
+	files := snapshot(w.dirs)
+	if len(files) == len(w.files) {
+		same := true
+		for path, s := range files {
+			if w.files[path] != s {
+				same = false
+				break
+			}
+		}
+
+		if same {
+			return false
+		}
+	}
+
+	w.files = files
+	return true
+}
+
+// regenerate reads the API and updates the outputs whose contents changed,
+// leaving the others untouched so tools watching them aren't triggered.
+func (w *watchedAPI) regenerate(c *config.Config) {
+	defer func() {
+		w.files = snapshot(w.dirs)
+	}()
+
+	d, err := parse(c, w.api)
+	if err != nil {
+		report(err)
+		return
+	}
+
+	w.dirs = nil
+	seen := make(map[string]bool)
+	for _, f := range d.Files {
+		dir := filepath.Dir(f)
+		if !seen[dir] {
+			seen[dir] = true
+			w.dirs = append(w.dirs, dir)
+		}
+	}
+
+	tmp, err := os.MkdirTemp("", "gobridge")
+	if err != nil {
+		report(err)
+		return
+	}
+	defer os.RemoveAll(tmp)
+
+	written, err := generateFrom(c, w.api, d, tmp)
+	if err != nil {
+		report(err)
+		return
+	}
+
+	changed, err := changedOutputs(tmp, written)
+	if err != nil {
+		report(err)
+		return
+	}
+
+	for _, path := range changed {
+		b, err := os.ReadFile(filepath.Join(tmp, path))
+		if err != nil {
+			report(err)
+			return
+		}
+
+		err = os.MkdirAll(filepath.Dir(path), os.ModePerm)
+		if err != nil {
+			report(err)
+			return
+		}
+
+		err = os.WriteFile(path, b, 0o644)
+		if err != nil {
+			report(err)
+			return
+		}
+
+		fmt.Fprintf(os.Stderr, "%s updated %s\n", time.Now().Format(time.TimeOnly), path)
+	}
+}
+
+// snapshot returns the state of the source files in the directories.
+func snapshot(dirs []string) map[string]fileState {
+	res := make(map[string]fileState)
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+
+		for _, e := range entries {
+			name := e.Name()
+			if e.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+				continue
+			}
+
+			path := filepath.Join(dir, name)
+			res[path] = statFile(path)
+		}
+	}
+
+	return res
+}
+
+func statFile(path string) fileState {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return fileState{}
+	}
+
+	return fileState{modTime: fi.ModTime(), size: fi.Size()}
+}
+
+// report prints an error without stopping, keeping diagnostics in the
+// file:line:col form editors recognise.
+func report(err error) {
+	fmt.Fprintln(os.Stderr, err)
+}